@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Eyemetric/alpr_service/internal/config"
+	"github.com/Eyemetric/alpr_service/internal/migrate"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const usage = `usage: alpr_service <command> [flags]
+
+commands:
+  serve [-role R]        http server for role api, worker or all (the default with no command)
+  worker                 worker role without an http server
+  migrate                apply or revert schema migrations
+  ingest-file            replay a JSON or NDJSON file of plate reads through ingest
+  hotlist import         load a hotlist document from a file
+  deadletter reprocess   run dead-lettered reads through ingest again
+
+run alpr_service <command> -h for the flags of a command.
+`
+
+type command struct {
+	name string
+	run  func(args []string)
+}
+
+// every command reads the same config (ALPR_CONFIG + env) as the server.
+var commands = []command{
+	{"serve", runServe},
+	{"worker", runWorker},
+	{"migrate", runMigrate},
+	{"ingest-file", runIngestFile},
+	{"hotlist", runHotlist},
+	{"deadletter", runDeadletter},
+}
+
+func dispatch(args []string) {
+	//no command keeps the old behaviour of just starting the server
+	if len(args) == 0 {
+		runServe(nil)
+		return
+	}
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			cmd.run(args[1:])
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+	os.Exit(2)
+}
+
+func loadConfig() config.Config {
+	conf, err := config.Load()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	return conf
+}
+
+// openDB connects and makes sure the schema is what this build expects,
+// applying pending migrations first when db.auto_migrate is on.
+func openDB(ctx context.Context, conf config.Config) *pgxpool.Pool {
+	dbPool := connectDB(ctx, conf)
+
+	migrator := migrate.New(dbPool)
+	if conf.DB.AutoMigrate {
+		applied, err := migrator.Up(ctx, 0)
+		if err != nil {
+			log.Fatalf("migrating database: %v", err)
+		}
+		for _, m := range applied {
+			log.Printf("applied migration %04d_%s\n", m.Version, m.Name)
+		}
+	}
+	version, err := migrator.Check(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("database schema version %d (build expects %d)\n", version, migrate.Expected())
+	return dbPool
+}
+
+// signalContext is cancelled on SIGINT/SIGTERM so long running commands can stop cleanly.
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Eyemetric/alpr_service/internal/api/hotlist"
+	"github.com/Eyemetric/alpr_service/internal/repository"
+)
+
+const hotlistUsage = `usage: alpr_service hotlist import FILE
+
+FILE holds the same document NJSNAP posts to /api/alpr/v1/hotlist, - reads stdin.
+`
+
+func runHotlist(args []string) {
+	if len(args) == 0 || args[0] != "import" {
+		fmt.Fprint(os.Stderr, hotlistUsage)
+		os.Exit(2)
+	}
+	fs := flag.NewFlagSet("hotlist import", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, hotlistUsage) }
+	fs.Parse(args[1:])
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	doc, err := readInput(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	dbPool := openDB(ctx, loadConfig())
+	defer dbPool.Close()
+
+	if err := hotlist.AddHotlist(ctx, doc, repository.NewPgxAlprRepo(dbPool)); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Eyemetric/alpr_service/internal/db"
+	"github.com/Eyemetric/alpr_service/internal/repository"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const ingestFileUsage = `usage: alpr_service ingest-file FILE
+
+replays PlateSmart docs through ingest, same as posting them to /api/alpr/v1/add without validation,
+so anything the db can't store is dead-lettered as usual. FILE is a JSON doc, a JSON array of docs
+or NDJSON, - reads stdin.
+`
+
+func runIngestFile(args []string) {
+	fs := flag.NewFlagSet("ingest-file", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, ingestFileUsage) }
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	in, err := openInput(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+
+	ctx, stop := signalContext()
+	defer stop()
+	dbPool := openDB(ctx, loadConfig())
+	defer dbPool.Close()
+	repo := repository.NewPgxAlprRepo(dbPool)
+
+	tally := map[string]int{}
+	n := 0
+	err = eachDoc(in, func(doc []byte) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		n++
+		res, err := repo.IngestPlateRead(ctx, doc)
+		if err != nil {
+			return fmt.Errorf("doc %d: %w", n, err)
+		}
+		if res.Outcome != repository.IngestOK {
+			fmt.Printf("doc %d: %s\n", n, describe(res))
+		}
+		tally[res.Outcome]++
+		return nil
+	})
+	printTally(tally)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// eachDoc calls fn with every JSON value in r. a top level array is unpacked into its elements,
+// anything else is read as a stream of values, which covers a single doc and NDJSON.
+func eachDoc(r io.Reader, fn func(doc []byte) error) error {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+
+	first, err := firstByte(br)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	array := first == '['
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		if array && !dec.More() {
+			_, err := dec.Token()
+			return err
+		}
+		var doc json.RawMessage
+		err := dec.Decode(&doc)
+		if err == io.EOF && !array {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading docs: %w", err)
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+}
+
+// firstByte peeks past leading whitespace without consuming anything the decoder needs.
+func firstByte(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
+
+const deadletterUsage = `usage: alpr_service deadletter reprocess [flags]
+
+runs dead-lettered docs through ingest again, oldest first. a doc that is stored (or was already stored)
+is removed from the deadletter table, one that fails again is dead-lettered under a new id.
+
+flags:
+`
+
+func runDeadletter(args []string) {
+	if len(args) == 0 || args[0] != "reprocess" {
+		fmt.Fprint(os.Stderr, deadletterUsage)
+		os.Exit(2)
+	}
+	fs := flag.NewFlagSet("deadletter reprocess", flag.ExitOnError)
+	id := fs.Int64("id", 0, "reprocess only this deadletter id")
+	stage := fs.String("stage", "", "only rows that failed at this stage (staging or alpr-insert)")
+	since := fs.Duration("since", 0, "only rows that failed within this long, 0 means all")
+	limit := fs.Int("limit", 1000, "most rows to reprocess in one run")
+	dryRun := fs.Bool("dry-run", false, "list the ids that would be reprocessed")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, deadletterUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args[1:])
+
+	ctx, stop := signalContext()
+	defer stop()
+	dbPool := openDB(ctx, loadConfig())
+	defer dbPool.Close()
+	repo := repository.NewPgxAlprRepo(dbPool)
+
+	ids := []int64{*id}
+	if *id == 0 {
+		var err error
+		if ids, err = repo.ListDeadletter(ctx, deadletterFilter(*stage, *since, *limit)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *dryRun {
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+		fmt.Printf("%d deadletter rows would be reprocessed\n", len(ids))
+		return
+	}
+
+	tally := map[string]int{}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		res, err := repo.ReprocessDeadletter(ctx, id)
+		if err != nil {
+			printTally(tally)
+			log.Fatal(err)
+		}
+		fmt.Printf("deadletter %d: %s\n", id, describe(res))
+		tally[res.Outcome]++
+	}
+	printTally(tally)
+}
+
+func deadletterFilter(stage string, since time.Duration, limit int) db.ListDeadletterParams {
+	params := db.ListDeadletterParams{
+		Stage:   stage,
+		Since:   pgtype.Timestamptz{Time: time.Unix(0, 0), Valid: true},
+		MaxRows: int32(limit),
+	}
+	if since > 0 {
+		params.Since.Time = time.Now().Add(-since)
+	}
+	return params
+}
+
+func describe(res repository.IngestResult) string {
+	switch {
+	case res.ID != 0:
+		return fmt.Sprintf("%s:%s (alpr id %d)", res.Outcome, res.Stage, res.ID)
+	case res.Stage != "":
+		return res.Outcome + ":" + res.Stage
+	default:
+		return res.Outcome
+	}
+}
+
+func printTally(tally map[string]int) {
+	outcomes := make([]string, 0, len(tally))
+	for outcome := range tally {
+		outcomes = append(outcomes, outcome)
+	}
+	sort.Strings(outcomes)
+	for _, outcome := range outcomes {
+		fmt.Printf("%-12s %d\n", outcome, tally[outcome])
+	}
+}
+
+// openInput opens path, - is stdin.
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+func readInput(path string) ([]byte, error) {
+	in, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	return io.ReadAll(in)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Eyemetric/alpr_service/internal/config"
+	"github.com/Eyemetric/alpr_service/internal/migrate"
+)
+
+const migrateUsage = `usage: alpr_service migrate <command>
+
+commands:
+  up [-to N]       apply pending migrations, or up to version N
+  down [-steps N]  revert the last N migrations (default 1), the baseline can't be reverted
+  version          print the database and build schema versions
+`
+
+// runMigrate handles `alpr_service migrate ...`. Uses the same config as the service, only the db settings have to be there.
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	to := fs.Int("to", 0, "target version for up, 0 means latest")
+	steps := fs.Int("steps", 1, "number of migrations to revert for down")
+	fs.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	fs.Parse(args[1:])
+
+	conf, err := config.LoadDB()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	ctx := context.Background()
+	pool := connectDB(ctx, conf)
+	defer pool.Close()
+	migrator := migrate.New(pool)
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx, *to)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("nothing to apply")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "version":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("database: %d\nbuild:    %d\n", version, migrate.Expected())
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/Eyemetric/alpr_service/internal/api/alert"
+	"github.com/Eyemetric/alpr_service/internal/api/cameras"
+	"github.com/Eyemetric/alpr_service/internal/api/retention"
+	"github.com/Eyemetric/alpr_service/internal/api/searchjobs"
+	"github.com/Eyemetric/alpr_service/internal/api/wasabi"
+	"github.com/Eyemetric/alpr_service/internal/config"
+	"github.com/Eyemetric/alpr_service/internal/leader"
+	"github.com/Eyemetric/alpr_service/internal/repository"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// runWorker is the worker role without an http server.
+func runWorker(args []string) {
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	fs.Parse(args)
+
+	conf := loadConfig()
+	conf.Role = config.RoleWorker
+	log.Println("------------- starting alert worker ------------")
+	log.Printf("effective config:\n%s", conf)
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	dbPool := openDB(ctx, conf)
+	defer dbPool.Close()
+
+	wasabi, err := wasabi.NewWasabi(conf.S3.Host, conf.S3.Region, conf.S3.PresignTTL)
+	if err != nil {
+		log.Fatalf("unable to create wasabi client: %v", err)
+	}
+
+	repo := repository.NewPgxAlprRepo(dbPool)
+	startWorker(ctx, conf, dbPool, repo, wasabi, newPurger(conf, dbPool, repo))
+
+	<-ctx.Done()
+	log.Println("alert worker stopping")
+}
+
+// startWorker starts this instance's alert worker and search job workers, which every replica runs, and enters
+// it in the leader election for the singleton jobs, which only one replica runs at a time.
+func startWorker(ctx context.Context, conf config.Config, dbPool *pgxpool.Pool, repo *repository.PgxAlprRepo, wasabi *wasabi.Wasabi, purger *retention.Purger) *alert.WorkerStatus {
+	status, err := alert.StartAlertListener(ctx, repo, wasabi, newAlertConfig(conf))
+	if err != nil {
+		log.Fatalf("could not start alert listener: %v", err)
+	}
+	log.Printf("alert worker %s started\n", conf.WorkerID)
+
+	jobs := []leader.Job{
+		{Name: "reclaimer", Key: leader.KeyReclaimer, Run: func(ctx context.Context) {
+			alert.RunReclaimer(ctx, repo, conf.Alert.ReclaimInterval)
+		}},
+		{Name: "event dispatcher", Key: leader.KeyDispatcher, Run: func(ctx context.Context) {
+			alert.RunEventDispatcher(ctx, repo, conf.Alert.PollInterval)
+		}},
+		{Name: "search job cleanup", Key: leader.KeySearchJobs, Run: func(ctx context.Context) {
+			searchjobs.RunCleanup(ctx, repo, conf.Search.JobRetention)
+		}},
+		{Name: "camera refresh", Key: leader.KeyCameras, Run: func(ctx context.Context) {
+			cameras.RunRefresh(ctx, repo)
+		}},
+	}
+	if conf.Retention.Enabled {
+		jobs = append(jobs, leader.Job{Name: "retention", Key: leader.KeyRetention, Run: func(ctx context.Context) {
+			purger.Loop(ctx, conf.Retention.Interval, conf.Retention.DryRun)
+		}})
+	}
+	for _, job := range jobs {
+		leader.Start(ctx, dbPool, leader.DefaultRetry, job)
+	}
+
+	if conf.Search.JobWorkers > 0 {
+		runner := &searchjobs.Runner{
+			DB:       dbPool,
+			Repo:     repo,
+			WorkerID: conf.WorkerID,
+			Workers:  conf.Search.JobWorkers,
+			MaxRows:  conf.Search.JobMaxRows,
+		}
+		go runner.Run(ctx)
+		log.Printf("%d search job workers started\n", conf.Search.JobWorkers)
+	}
+	return status
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/Eyemetric/alpr_service/internal/api/wasabi"
@@ -54,18 +55,25 @@ type PlateHit struct {
 }
 
 type AlertConfig struct {
-	PlateHitUrl string
-	AuthToken   string
-	SendTimeout time.Duration
+	PlateHitUrl  string
+	AuthToken    string
+	SendTimeout  time.Duration
+	PollInterval time.Duration
+	Bucket       string //wasabi bucket holding the images we presign links to
+	TLS          TLSConfig
+	//shared secret for HMAC signing of hits. signing is off when empty
+	SigningSecret string
+	//recorded as alerts.locked_by on the alerts this process claims, unique per instance
+	WorkerID string
 }
 
 type Sender interface {
-	Send(ctx context.Context, p PlateHits) (*http.Response, error)
+	Send(ctx context.Context, alertID int64, p PlateHits) (int, error)
 }
 
 type SimSender struct{ FailureOnOddPlate bool }
 
-func (s SimSender) Send(ctx context.Context, p PlateHits) (*http.Response, error) {
+func (s SimSender) Send(ctx context.Context, alertID int64, p PlateHits) (int, error) {
 
 	hit := p.Plates[0]
 	// if s.FailureOnOddPlate && (hit.) {
@@ -73,7 +81,7 @@ func (s SimSender) Send(ctx context.Context, p PlateHits) (*http.Response, error
 	// }
 	fmt.Printf("send alert plate=%s camera=%s\n", hit.PlateNumber, hit.CameraName)
 	//fmt.Printf("send alert id=%d (plateid=%d, hotlist=%d)\n", j.ID, j.PlateID, j.HotlistID)
-	return nil, nil
+	return http.StatusOK, nil
 }
 
 func toString(t pgtype.Text) string {
@@ -93,7 +101,7 @@ func toTime(ts pgtype.Timestamp) (time.Time, bool) {
 	return ts.Time, true
 }
 
-func buildHitDoc(ctx context.Context, job Job, repo repository.ALPRRepository, w *wasabi.Wasabi) (PlateHits, error) {
+func buildHitDoc(ctx context.Context, job Job, repo repository.ALPRRepository, w *wasabi.Wasabi, bucket string) (PlateHits, error) {
 
 	params := db.GetPlateHitParams{
 		PlateID:   job.PlateID,
@@ -141,7 +149,7 @@ func buildHitDoc(ctx context.Context, job Job, repo repository.ALPRRepository, w
 		}
 
 		//build wasabi image links.
-		phit = createImageLinks(phit, w)
+		phit = createImageLinks(phit, w, bucket)
 		plateHits = append(plateHits, phit)
 
 	}
@@ -156,11 +164,10 @@ func buildHitDoc(ctx context.Context, job Job, repo repository.ALPRRepository, w
 	return ph, nil
 }
 
-func createImageLinks(plateHit PlateHit, w *wasabi.Wasabi) PlateHit {
+func createImageLinks(plateHit PlateHit, w *wasabi.Wasabi, bucket string) PlateHit {
 
 	vehicle_img := fmt.Sprintf("alpr/%s/%s", plateHit.SourceID, plateHit.ImageID)
 	plate_img := fmt.Sprintf("alpr-plate/%s/%s", plateHit.SourceID, plateHit.ImageID)
-	bucket := "njsnap"
 
 	vehicle_url, err := w.PresignUrl(bucket, vehicle_img)
 	if err == nil {
@@ -175,27 +182,79 @@ func createImageLinks(plateHit PlateHit, w *wasabi.Wasabi) PlateHit {
 	return plateHit
 }
 
-// A simple database poll to check for plate hits every 5 seconds.
+// how often the alert queue is polled when the config doesn't say.
+const DefaultPollInterval = 5 * time.Second
+
+// WorkerStatus lets the readiness check see whether the alert goroutine is still polling.
+// A poll counts as successful when the claim query returns without error, even if nothing was due,
+// and so does every send attempt, whatever NJSNAP answered.
+type WorkerStatus struct {
+	lastLoop     atomic.Int64 //unix nanos of the last successful poll, 0 if never
+	pollInterval time.Duration
+	sendTimeout  time.Duration
+}
+
+func NewWorkerStatus(pollInterval, sendTimeout time.Duration) *WorkerStatus {
+	return &WorkerStatus{pollInterval: pollInterval, sendTimeout: sendTimeout}
+}
+
+func (w *WorkerStatus) PollInterval() time.Duration {
+	return w.pollInterval
+}
+
+// MaxAge is how long the worker can go without a loop before it's stuck. a send blocked on a slow
+// NJSNAP takes up to the send timeout, so that's allowed on top of a few polls.
+func (w *WorkerStatus) MaxAge() time.Duration {
+	return w.sendTimeout + 3*w.pollInterval
+}
+
+// MarkLoop records a successful poll or send attempt.
+func (w *WorkerStatus) MarkLoop() {
+	w.lastLoop.Store(time.Now().UnixNano())
+}
+
+// LastLoop returns the time of the last successful poll, or the zero time if the worker never got one in.
+func (w *WorkerStatus) LastLoop() time.Time {
+	n := w.lastLoop.Load()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// A simple database poll to check for plate hits every PollInterval (5 seconds by default).
 // func StartAlertListener(ctx context.Context, pool *pgxpool.Pool, wasabi *wasabi.Wasabi, conf AlertConfig) error {
-func StartAlertListener(ctx context.Context, repo repository.ALPRRepository, wasabi *wasabi.Wasabi, conf AlertConfig) error {
+func StartAlertListener(ctx context.Context, repo repository.ALPRRepository, wasabi *wasabi.Wasabi, conf AlertConfig) (*WorkerStatus, error) {
 
 	const (
-		pollInterval = 5 * time.Second
-		batchSize    = 1
+		batchSize = 1
 	)
 
 	sendTimeout := conf.SendTimeout
+	pollInterval := conf.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
 	//q := db.New(pool)
 
-	sender := NewPlateSender(conf)
+	sender, err := NewPlateSender(conf)
+	if err != nil {
+		return nil, err
+	}
+	workerID := conf.WorkerID
+	if workerID == "" {
+		workerID = "worker-1"
+	}
+	status := NewWorkerStatus(pollInterval, sendTimeout)
 
 	go func() {
 		drainQueue := func() bool {
-			rows, err := repo.ClaimDue(ctx, db.ClaimDueParams{Batch: batchSize, WorkerID: "worker-1"})
+			rows, err := repo.ClaimDue(ctx, db.ClaimDueParams{Batch: batchSize, WorkerID: workerID})
 			if err != nil {
 				log.Printf("claim error: %v", err)
 				return false
 			}
+			status.MarkLoop()
 
 			log.Printf("polling queue: %d results\n", len(rows))
 			//nothing to claim
@@ -205,18 +264,19 @@ func StartAlertListener(ctx context.Context, repo repository.ALPRRepository, was
 
 			for _, row := range rows {
 				hitJob := Job{ID: row.ID, PlateID: row.PlateID, HotlistID: row.HotlistID}
-				plateHits, err := buildHitDoc(ctx, hitJob, repo, wasabi)
+				plateHits, err := buildHitDoc(ctx, hitJob, repo, wasabi, conf.Bucket)
 				if err != nil {
 					continue
 				}
 				sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
 				//err = sender.Send(ctx context.Context, hits PlateHits)
-				statusCode, err := sender.Send(sendCtx, plateHits)
+				statusCode, err := sender.Send(sendCtx, hitJob.ID, plateHits)
 				//sendRes := sender.Send(sendCtx, plateHits)
 				fmt.Println("-------  Plate Hit Result----- ")
 				fmt.Printf("code: %d.  Err: %s", statusCode, err)
 
 				cancel()
+				status.MarkLoop()
 
 				if err != nil {
 					fmt.Println("-------  FAIL ----- ")
@@ -256,5 +316,5 @@ func StartAlertListener(ctx context.Context, repo repository.ALPRRepository, was
 		}
 	}() //start go routing
 
-	return nil
+	return status, nil
 }
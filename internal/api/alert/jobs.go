@@ -0,0 +1,63 @@
+package alert
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Eyemetric/alpr_service/internal/repository"
+)
+
+// singleton jobs, run by whichever instance holds the leader lock (see internal/leader)
+
+const DefaultReclaimInterval = 30 * time.Second
+
+// RunReclaimer puts alerts stuck in processing past their deadline back in the queue every interval.
+// a worker that crashes mid send leaves its claimed alerts in processing, this is what gets them sent.
+func RunReclaimer(ctx context.Context, repo repository.ALPRRepository, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultReclaimInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := repo.ReclaimStuck(ctx)
+			if err != nil {
+				log.Printf("reclaimer: %v\n", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("reclaimer: %d stuck alerts back in the queue\n", n)
+			}
+		}
+	}
+}
+
+// RunEventDispatcher logs hotlist_alert_events (vendor_down, vendor_recovered) once each, every interval.
+func RunEventDispatcher(ctx context.Context, repo repository.ALPRRepository, interval time.Duration) {
+	const batch = 100
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			events, err := repo.ClaimAlertEvents(ctx, batch)
+			if err != nil {
+				log.Printf("event dispatcher: %v\n", err)
+				continue
+			}
+			for _, ev := range events {
+				log.Printf("ALERT EVENT %s id=%d at=%s details=%s\n", ev.Kind, ev.ID, ev.CreatedAt.Time.Format(time.RFC3339), ev.Details)
+			}
+		}
+	}
+}
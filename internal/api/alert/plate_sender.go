@@ -3,12 +3,13 @@ package alert
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // create http client
@@ -16,21 +17,41 @@ type PlateHitSender struct {
 	client *http.Client
 	base   string
 	token  string
+	secret []byte //hmac signing secret, nil when signing is off
 }
 
-func NewPlateSender(conf AlertConfig) *PlateHitSender {
+func NewPlateSender(conf AlertConfig) (*PlateHitSender, error) {
+
+	tlsConf, err := conf.TLS.build()
+	if err != nil {
+		return nil, fmt.Errorf("building tls config: %w", err)
+	}
+	if conf.TLS.InsecureSkipVerify {
+		log.Println("WARNING: plate hit sender is not verifying tls certificates")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConf
 
 	return &PlateHitSender{
 		client: &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
-			Timeout: conf.SendTimeout},
-		base:  conf.PlateHitUrl,
-		token: conf.AuthToken,
+			Transport: transport,
+			Timeout:   conf.SendTimeout},
+		base:   conf.PlateHitUrl,
+		token:  conf.AuthToken,
+		secret: signingSecret(conf.SigningSecret),
+	}, nil
+}
+
+func signingSecret(s string) []byte {
+	if s == "" {
+		return nil
 	}
+	return []byte(s)
 }
 
-func (p PlateHitSender) Send(ctx context.Context, hits PlateHits) (int, error) {
+// Send posts the hits for a single alert. alertID keys the request so retries can be deduped by the receiver.
+func (p PlateHitSender) Send(ctx context.Context, alertID int64, hits PlateHits) (int, error) {
 
 	body, err := json.Marshal(hits)
 	if err != nil {
@@ -44,6 +65,12 @@ func (p PlateHitSender) Send(ctx context.Context, hits PlateHits) (int, error) {
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("AuthToken", p.token)
+	req.Header.Set(HeaderIdempotencyKey, IdempotencyKey(alertID))
+	if p.secret != nil {
+		if err := sign(req, p.secret, body, time.Now()); err != nil {
+			return 0, err
+		}
+	}
 
 	resp, err := p.client.Do(req)
 
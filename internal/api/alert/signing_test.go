@@ -0,0 +1,57 @@
+package alert
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	body := []byte(`{"plateHits":[{"plateNumber":"TEST1234"}]}`)
+	now := time.Unix(1722289388, 0)
+
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com", nil)
+	if err := sign(req, secret, body, now); err != nil {
+		t.Fatal(err)
+	}
+
+	nonce, err := VerifySignature(req.Header, body, secret, DefaultMaxSkew, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if nonce != req.Header.Get(HeaderNonce) {
+		t.Errorf("expected nonce %s, got %s", req.Header.Get(HeaderNonce), nonce)
+	}
+
+	tests := []struct {
+		name   string
+		body   []byte
+		secret []byte
+		at     time.Time
+		want   error
+	}{
+		{name: "tampered body", body: []byte(`{"plateHits":[]}`), secret: secret, at: now, want: ErrBadSignature},
+		{name: "wrong secret", body: body, secret: []byte("another-secret-another-secret-xx"), at: now, want: ErrBadSignature},
+		{name: "stale", body: body, secret: secret, at: now.Add(DefaultMaxSkew + time.Second), want: ErrStaleSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifySignature(req.Header, tt.body, tt.secret, DefaultMaxSkew, tt.at)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := VerifySignature(http.Header{}, body, secret, DefaultMaxSkew, now); !errors.Is(err, ErrMissingSignature) {
+		t.Fatalf("expected missing signature, got %v", err)
+	}
+}
+
+func TestIdempotencyKeyStable(t *testing.T) {
+	if IdempotencyKey(42) != IdempotencyKey(42) || IdempotencyKey(42) == IdempotencyKey(43) {
+		t.Fatal("idempotency key must be stable per alert and differ between alerts")
+	}
+}
@@ -0,0 +1,107 @@
+package alert
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSConfig controls how we verify NJSNAP's certificate and whether we present one of our own.
+// The zero value verifies against the system roots, which is what production should use.
+type TLSConfig struct {
+	CAFile   string //extra PEM bundle trusted on top of the system roots
+	CertFile string //client certificate for mutual TLS
+	KeyFile  string
+	//sha256 of the server's SubjectPublicKeyInfo, base64 encoded (curl's --pinnedpubkey format, "sha256//" prefix optional).
+	//when set, at least one cert in the verified chain must match a pin, or the leaf itself when insecure.
+	Pins []string
+	//turns off certificate verification. only for local testing against tools/hit_receiver.
+	InsecureSkipVerify bool
+}
+
+func (t TLSConfig) build() (*tls.Config, error) {
+	conf := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading ca bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in ca bundle %s", t.CAFile)
+		}
+		conf.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, errors.New("client cert and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(t.Pins) > 0 {
+		pins, err := parsePins(t.Pins)
+		if err != nil {
+			return nil, err
+		}
+		//VerifyConnection runs after normal verification (or instead of it when insecure), so pins hold either way
+		conf.VerifyConnection = func(cs tls.ConnectionState) error {
+			return checkPins(cs, pins, t.InsecureSkipVerify)
+		}
+	}
+
+	return conf, nil
+}
+
+func parsePins(raw []string) (map[[sha256.Size]byte]bool, error) {
+	pins := make(map[[sha256.Size]byte]bool, len(raw))
+	for _, p := range raw {
+		p = strings.TrimPrefix(strings.TrimSpace(p), "sha256//")
+		b, err := base64.StdEncoding.DecodeString(p)
+		if err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("invalid pin %q: must be a base64 sha256 digest", p)
+		}
+		var key [sha256.Size]byte
+		copy(key[:], b)
+		pins[key] = true
+	}
+	return pins, nil
+}
+
+// checkPins only trusts certs that were verified. the server can send anything after its leaf, and a pinned
+// cert is public, so a pin matching some extra cert it appended proves nothing.
+// unverified (insecure) there's no chain, only the leaf's own key counts.
+func checkPins(cs tls.ConnectionState, pins map[[sha256.Size]byte]bool, insecure bool) error {
+	var certs []*x509.Certificate
+	switch {
+	case !insecure:
+		for _, chain := range cs.VerifiedChains {
+			certs = append(certs, chain...)
+		}
+	case len(cs.PeerCertificates) > 0:
+		certs = cs.PeerCertificates[:1]
+	}
+	for _, cert := range certs {
+		if pins[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+			return nil
+		}
+	}
+	return errors.New("server certificate does not match any pinned public key")
+}
@@ -0,0 +1,151 @@
+package alert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTLSSender(t *testing.T, url string, tlsConf TLSConfig) *PlateHitSender {
+	t.Helper()
+	sender, err := NewPlateSender(AlertConfig{PlateHitUrl: url, SendTimeout: 5 * time.Second, TLS: tlsConf})
+	if err != nil {
+		t.Fatalf("building sender: %v", err)
+	}
+	return sender
+}
+
+func TestSenderTLSVerification(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	spki := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+	goodPin := base64.StdEncoding.EncodeToString(spki[:])
+	badPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	hits := PlateHits{Plates: []PlateHit{{PlateNumber: "TEST1234"}}}
+
+	tests := []struct {
+		name    string
+		conf    TLSConfig
+		wantErr string
+	}{
+		{name: "verifies by default", conf: TLSConfig{}, wantErr: "certificate"},
+		{name: "insecure skips verification", conf: TLSConfig{InsecureSkipVerify: true}},
+		{name: "matching pin", conf: TLSConfig{InsecureSkipVerify: true, Pins: []string{"sha256//" + goodPin}}},
+		{name: "pin mismatch", conf: TLSConfig{InsecureSkipVerify: true, Pins: []string{badPin}}, wantErr: "pinned"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := newTLSSender(t, server.URL, tt.conf)
+			_, err := sender.Send(context.Background(), 1, hits)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestInvalidPin(t *testing.T) {
+	_, err := NewPlateSender(AlertConfig{TLS: TLSConfig{Pins: []string{"not-a-pin"}}})
+	if err == nil {
+		t.Fatal("expected invalid pin to be rejected")
+	}
+}
+
+// newCert makes a cert signed by parent, or self signed when parent is nil.
+func newCert(t *testing.T, name string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func pin(cert *x509.Certificate) string {
+	spki := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(spki[:])
+}
+
+// a server with a valid chain can't satisfy a pin by tacking the (public) pinned cert on after its own leaf
+func TestPinsOnlyMatchVerifiedChain(t *testing.T) {
+	ca, caKey := newCert(t, "test ca", true, nil, nil)
+	leaf, leafKey := newCert(t, "impostor", false, ca, caKey)
+	pinned, _ := newCert(t, "njsnap", false, nil, nil)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{
+		Certificate: [][]byte{leaf.Raw, pinned.Raw},
+		PrivateKey:  leafKey,
+	}}}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hits := PlateHits{Plates: []PlateHit{{PlateNumber: "TEST1234"}}}
+
+	_, err := newTLSSender(t, server.URL, TLSConfig{CAFile: caFile, Pins: []string{pin(pinned)}}).Send(context.Background(), 1, hits)
+	if err == nil || !strings.Contains(err.Error(), "pinned") {
+		t.Fatalf("expected a pin on an appended cert to be refused, got %v", err)
+	}
+	_, err = newTLSSender(t, server.URL, TLSConfig{InsecureSkipVerify: true, Pins: []string{pin(pinned)}}).Send(context.Background(), 1, hits)
+	if err == nil || !strings.Contains(err.Error(), "pinned") {
+		t.Fatalf("insecure should only pin the leaf, got %v", err)
+	}
+
+	for _, c := range []*x509.Certificate{ca, leaf} {
+		if _, err := newTLSSender(t, server.URL, TLSConfig{CAFile: caFile, Pins: []string{pin(c)}}).Send(context.Background(), 1, hits); err != nil {
+			t.Errorf("pin on verified %s should pass: %v", c.Subject.CommonName, err)
+		}
+	}
+}
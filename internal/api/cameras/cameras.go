@@ -0,0 +1,82 @@
+package cameras
+
+/* Cameras lists every camera we've had a read from so search UIs can offer a picker for camera_names.
+The list comes from the cameras table, which RunRefresh keeps up to date from the reads ingested since its
+last run, so a new camera shows up within a couple of minutes.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Eyemetric/alpr_service/internal/api/search"
+	"github.com/Eyemetric/alpr_service/internal/repository"
+)
+
+const RefreshInterval = time.Minute
+
+type Camera struct {
+	Name      string          `json:"camera_name"`
+	SourceID  *string         `json:"source_id"`
+	FirstSeen time.Time       `json:"first_seen"`
+	LastSeen  time.Time       `json:"last_seen"`
+	ReadCount int64           `json:"read_count"`
+	Location  json.RawMessage `json:"location"` //{lat, lon} of its latest read, same shape as search results
+}
+
+// Pattern turns the q filter into an ilike pattern. plain text matches anywhere in the name,
+// % is a wildcard like camera_names in a search. _ is just an underscore.
+func Pattern(q string) string {
+	q = search.EscapeUnderscore(strings.TrimSpace(q))
+	if q == "" || strings.Contains(q, "%") {
+		return q
+	}
+	return "%" + q + "%"
+}
+
+// List returns the cameras whose name matches q, case-insensitive, sorted by name. Every camera when q is empty.
+func List(ctx context.Context, q string, repo repository.ALPRRepository) ([]Camera, error) {
+	rows, err := repo.ListCameras(ctx, Pattern(q))
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Camera, 0, len(rows))
+	for _, row := range rows {
+		cam := Camera{
+			Name:      row.CameraName,
+			FirstSeen: row.FirstSeen.Time,
+			LastSeen:  row.LastSeen.Time,
+			ReadCount: row.ReadCount,
+			Location:  row.Location,
+		}
+		if row.SourceID.Valid {
+			cam.SourceID = &row.SourceID.String
+		}
+		if cam.Location == nil {
+			cam.Location = json.RawMessage("null")
+		}
+		list = append(list, cam)
+	}
+	return list, nil
+}
+
+// RunRefresh folds newly ingested reads into the cameras table every RefreshInterval.
+// a singleton job, run by the leader.
+func RunRefresh(ctx context.Context, repo repository.ALPRRepository) {
+	ticker := time.NewTicker(RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := repo.RefreshCameras(ctx); err != nil {
+				log.Printf("camera refresh: %v\n", err)
+			}
+		}
+	}
+}
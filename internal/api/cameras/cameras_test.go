@@ -0,0 +1,19 @@
+package cameras
+
+import "testing"
+
+func TestPattern(t *testing.T) {
+	cases := map[string]string{
+		"":                 "",
+		"mt pleasant":      "%mt pleasant%",
+		"  Route 10 ":      "%Route 10%",
+		"Route 10%":        "Route 10%",
+		"Mt Pleasant (E_)": `%Mt Pleasant (E\_)%`,
+		"CAM_%":            `CAM\_%`,
+	}
+	for q, want := range cases {
+		if got := Pattern(q); got != want {
+			t.Errorf("Pattern(%q) = %q, want %q", q, got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package health
+
+/* Health reports whether the service can actually do its job.
+Liveness only says the process is up. Readiness checks each dependency
+(postgres, wasabi, the alert worker) and reports them individually so
+whoever is looking at a 503 knows which piece is broken.
+*/
+
+import (
+	"context"
+	"time"
+
+	"github.com/Eyemetric/alpr_service/internal/api/alert"
+	"github.com/Eyemetric/alpr_service/internal/api/wasabi"
+	"github.com/Eyemetric/alpr_service/internal/repository"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusDown = "down"
+
+	// each dependency gets this long to answer before it's considered down
+	checkTimeout = 5 * time.Second
+)
+
+type Component struct {
+	Status  string         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// Healthy is true only when every component reported ok.
+func (r Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+type Checker struct {
+	Repo   repository.ALPRRepository
+	Wasabi *wasabi.Wasabi
+	Worker *alert.WorkerStatus //nil when this process doesn't run the alert worker
+	Bucket string
+	//an object we know exists in the bucket. HEADing it proves the credentials work.
+	//if empty we can only check that presigning works, which never leaves the process.
+	ProbeKey string
+}
+
+// Ready runs every check and rolls the results up into a single Report.
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := Report{
+		Status: StatusOK,
+		Components: map[string]Component{
+			"database": c.checkDatabase(ctx),
+			"wasabi":   c.checkWasabi(ctx),
+		},
+	}
+
+	if c.Worker != nil {
+		report.Components["alert_worker"] = c.checkWorker(ctx)
+	}
+
+	for _, comp := range report.Components {
+		if comp.Status != StatusOK {
+			report.Status = StatusDown
+			break
+		}
+	}
+	return report
+}
+
+func (c *Checker) checkDatabase(ctx context.Context) Component {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	if err := c.Repo.Ping(ctx); err != nil {
+		return Component{Status: StatusDown, Error: err.Error()}
+	}
+	return Component{Status: StatusOK}
+}
+
+func (c *Checker) checkWasabi(ctx context.Context) Component {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	if c.ProbeKey == "" {
+		if _, err := c.Wasabi.PresignUrl(c.Bucket, "health-check"); err != nil {
+			return Component{Status: StatusDown, Error: err.Error()}
+		}
+		return Component{Status: StatusOK, Details: map[string]any{"probe": "presign"}}
+	}
+
+	if err := c.Wasabi.HeadObject(ctx, c.Bucket, c.ProbeKey); err != nil {
+		return Component{Status: StatusDown, Error: err.Error(), Details: map[string]any{"probe": "head", "key": c.ProbeKey}}
+	}
+	return Component{Status: StatusOK, Details: map[string]any{"probe": "head", "key": c.ProbeKey}}
+}
+
+// the worker is down if it hasn't completed a poll or a send in a few intervals plus the send timeout.
+// a degraded scheduler mode (NJSNAP unreachable or slow) is reported but isn't our failure, so it stays ok.
+func (c *Checker) checkWorker(ctx context.Context) Component {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	maxAge := c.Worker.MaxAge()
+	details := map[string]any{}
+	comp := Component{Status: StatusOK, Details: details}
+
+	last := c.Worker.LastLoop()
+	if last.IsZero() {
+		details["last_loop"] = nil
+		comp.Status = StatusDown
+		comp.Error = "alert worker has not completed a poll"
+	} else {
+		details["last_loop"] = last.UTC()
+		if age := time.Since(last); age > maxAge {
+			comp.Status = StatusDown
+			comp.Error = "alert worker has not polled in " + age.Round(time.Second).String()
+		}
+	}
+
+	state, err := c.Repo.GetAlertState(ctx)
+	if err != nil {
+		comp.Status = StatusDown
+		comp.Error = "could not read scheduler state: " + err.Error()
+		return comp
+	}
+	details["scheduler_mode"] = state.Mode
+	details["phase_attempts"] = state.PhaseAttempts
+	if state.NextDueAt.Valid {
+		details["next_due_at"] = state.NextDueAt.Time.UTC()
+	}
+	if state.FirstFailedAt.Valid {
+		details["first_failed_at"] = state.FirstFailedAt.Time.UTC()
+	}
+
+	return comp
+}
@@ -0,0 +1,39 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Eyemetric/alpr_service/internal/api/alert"
+	"github.com/Eyemetric/alpr_service/internal/db"
+	"github.com/Eyemetric/alpr_service/internal/repository"
+)
+
+// fakeRepo only answers the scheduler state
+type fakeRepo struct {
+	repository.ALPRRepository
+}
+
+func (fakeRepo) GetAlertState(ctx context.Context) (db.GetAlertStateRow, error) {
+	return db.GetAlertStateRow{Mode: "p0_fast"}, nil
+}
+
+func TestWorkerBlockedOnSendIsReady(t *testing.T) {
+	//the last loop was a few polls ago, as when a send to NJSNAP is waiting out its timeout
+	worker := alert.NewWorkerStatus(time.Millisecond, time.Minute)
+	worker.MarkLoop()
+	time.Sleep(20 * time.Millisecond)
+
+	c := &Checker{Repo: fakeRepo{}, Worker: worker}
+	if comp := c.checkWorker(context.Background()); comp.Status != StatusOK {
+		t.Errorf("a worker waiting on a send should be ready: %+v", comp)
+	}
+
+	c.Worker = alert.NewWorkerStatus(time.Millisecond, time.Millisecond)
+	c.Worker.MarkLoop()
+	time.Sleep(20 * time.Millisecond)
+	if comp := c.checkWorker(context.Background()); comp.Status != StatusDown {
+		t.Errorf("a worker quiet for longer than a send should be down: %+v", comp)
+	}
+}
@@ -0,0 +1,179 @@
+package holds
+
+/* Legal holds keep plate reads needed for an investigation past the retention window.
+A hold is a case number plus what to keep: specific read ids, plates, and/or time windows.
+  - read_ids: those reads
+  - plates: every read of those plates, limited to the windows when windows are given
+  - windows on their own: every read inside them
+Matching reads are copied with their image keys into legal_hold_reads, which retention never touches,
+and the purge job re-captures before every chunk drop so reads that arrive after the hold are kept too.
+Releasing a hold deletes its copies and the reads fall back under normal retention.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Eyemetric/alpr_service/internal/db"
+	"github.com/Eyemetric/alpr_service/internal/repository"
+)
+
+var (
+	ErrInvalidHold = errors.New("invalid legal hold")
+	ErrNotFound    = errors.New("legal hold not found")
+)
+
+// plates are matched with ilike, so only allow characters that can't act as wildcards
+var plateRe = regexp.MustCompile(`^[A-Z0-9]{1,10}$`)
+
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type HoldRequest struct {
+	CaseNumber  string   `json:"case_number"`
+	Description string   `json:"description,omitempty"`
+	CreatedBy   string   `json:"created_by"`
+	ReadIDs     []string `json:"read_ids,omitempty"`
+	Plates      []string `json:"plates,omitempty"`
+	Windows     []Window `json:"windows,omitempty"`
+}
+
+// one row of legal_hold_criteria
+type criterion struct {
+	ReadID    string     `json:"read_id,omitempty"`
+	PlateNum  string     `json:"plate_num,omitempty"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+}
+
+// Validate reports every problem with the request at once.
+func (r *HoldRequest) Validate() error {
+	var errs []error
+	if strings.TrimSpace(r.CaseNumber) == "" {
+		errs = append(errs, errors.New("case_number is required"))
+	}
+	if strings.TrimSpace(r.CreatedBy) == "" {
+		errs = append(errs, errors.New("created_by is required"))
+	}
+	if len(r.ReadIDs) == 0 && len(r.Plates) == 0 && len(r.Windows) == 0 {
+		errs = append(errs, errors.New("at least one of read_ids, plates or windows is required"))
+	}
+	for i, id := range r.ReadIDs {
+		if strings.TrimSpace(id) == "" {
+			errs = append(errs, fmt.Errorf("read_ids[%d] is blank", i))
+		}
+	}
+	for i, p := range r.Plates {
+		if !plateRe.MatchString(strings.ToUpper(strings.TrimSpace(p))) {
+			errs = append(errs, fmt.Errorf("plates[%d] %q must be 1-10 letters or digits", i, p))
+		}
+	}
+	for i, w := range r.Windows {
+		if w.Start.IsZero() || w.End.IsZero() {
+			errs = append(errs, fmt.Errorf("windows[%d] needs a start and an end", i))
+		} else if !w.Start.Before(w.End) {
+			errs = append(errs, fmt.Errorf("windows[%d] start must be before end", i))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidHold, errors.Join(errs...))
+	}
+	return nil
+}
+
+// criteria expands the request into legal_hold_criteria rows.
+func (r *HoldRequest) criteria() []criterion {
+	var out []criterion
+	for _, id := range r.ReadIDs {
+		out = append(out, criterion{ReadID: strings.TrimSpace(id)})
+	}
+	for _, p := range r.Plates {
+		plate := strings.ToUpper(strings.TrimSpace(p))
+		if len(r.Windows) == 0 {
+			out = append(out, criterion{PlateNum: plate})
+		}
+		for _, w := range r.Windows {
+			out = append(out, criterion{PlateNum: plate, StartTime: &w.Start, EndTime: &w.End})
+		}
+	}
+	if len(r.Plates) == 0 {
+		for _, w := range r.Windows {
+			out = append(out, criterion{StartTime: &w.Start, EndTime: &w.End})
+		}
+	}
+	return out
+}
+
+// CreateHold validates the request, creates the hold, captures what it matches and returns the stored hold.
+func CreateHold(ctx context.Context, body []byte, repo repository.ALPRRepository) (json.RawMessage, error) {
+	var req HoldRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHold, err)
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	doc, err := json.Marshal(map[string]any{
+		"case_number": strings.TrimSpace(req.CaseNumber),
+		"description": req.Description,
+		"created_by":  strings.TrimSpace(req.CreatedBy),
+		"criteria":    req.criteria(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := repo.CreateLegalHold(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	return GetHold(ctx, id, repo)
+}
+
+// GetHold returns a hold with its criteria and every read it's holding.
+func GetHold(ctx context.Context, id int64, repo repository.ALPRRepository) (json.RawMessage, error) {
+	hold, err := repo.GetLegalHold(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get legal hold: %w", err)
+	}
+	if hold == nil {
+		return nil, ErrNotFound
+	}
+	return hold, nil
+}
+
+// ListHolds returns holds newest first, without their reads.
+func ListHolds(ctx context.Context, activeOnly bool, repo repository.ALPRRepository) ([]json.RawMessage, error) {
+	rows, err := repo.ListLegalHolds(ctx, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	holds := make([]json.RawMessage, len(rows))
+	for i, row := range rows {
+		holds[i] = row
+	}
+	return holds, nil
+}
+
+// ReleaseHold ends a hold. Its copies are deleted and the reads are back under normal retention.
+func ReleaseHold(ctx context.Context, id int64, releasedBy string, repo repository.ALPRRepository) error {
+	if strings.TrimSpace(releasedBy) == "" {
+		return fmt.Errorf("%w: released_by is required", ErrInvalidHold)
+	}
+	released, err := repo.ReleaseLegalHold(ctx, db.ReleaseLegalHoldParams{ID: id, ReleasedBy: releasedBy})
+	if err != nil {
+		return fmt.Errorf("failed to release legal hold: %w", err)
+	}
+	if !released {
+		return ErrNotFound //or already released, either way there's no active hold with that id
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package holds
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCriteria(t *testing.T) {
+	w1 := Window{Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)}
+	w2 := Window{Start: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name string
+		req  HoldRequest
+		want []string
+	}{
+		{"read ids", HoldRequest{ReadIDs: []string{"r1", " r2 "}}, []string{"r1||", "r2||"}},
+		{"plate for all time", HoldRequest{Plates: []string{"abc123"}}, []string{"|ABC123|"}},
+		{"plate in windows", HoldRequest{Plates: []string{"ABC123"}, Windows: []Window{w1, w2}}, []string{"|ABC123|2025-01-01", "|ABC123|2025-02-01"}},
+		{"window alone", HoldRequest{ReadIDs: []string{"r1"}, Windows: []Window{w1}}, []string{"r1||", "||2025-01-01"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range tt.req.criteria() {
+				start := ""
+				if c.StartTime != nil {
+					start = c.StartTime.Format(time.DateOnly)
+				}
+				got = append(got, c.ReadID+"|"+c.PlateNum+"|"+start)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("criteria = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+	valid := HoldRequest{CaseNumber: "2025-0042", CreatedBy: "det. smith", Plates: []string{"ABC123"}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("valid request rejected: %v", err)
+	}
+
+	bad := HoldRequest{
+		Plates:  []string{"AB%"},
+		Windows: []Window{{Start: now, End: now.Add(-time.Hour)}, {Start: now}},
+	}
+	err := bad.Validate()
+	if !errors.Is(err, ErrInvalidHold) {
+		t.Fatalf("err = %v, want ErrInvalidHold", err)
+	}
+	for _, want := range []string{"case_number", "created_by", "plates[0]", "windows[0] start", "windows[1] needs"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error doesn't mention %q: %v", want, err)
+		}
+	}
+
+	if err := (&HoldRequest{CaseNumber: "x", CreatedBy: "y"}).Validate(); err == nil {
+		t.Error("hold with nothing to hold accepted")
+	}
+}
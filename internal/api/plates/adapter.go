@@ -0,0 +1,207 @@
+package plates
+
+/* Adapters let camera systems other than PlateSmart feed the same ingest path.
+Each vendor adapter turns whatever that vendor posts into canonical Reads, and every
+Read is stored as a PlateSmart shaped document. That's the layout alpr_util.ingest_field_map
+and the rest of the schema already understand, so a new vendor never needs a schema change.
+The vendor's original record is kept under "raw" in the stored doc.
+
+PlateSmart documents are stored exactly as received.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const DefaultVendor = "platesmart"
+
+type Adapter interface {
+	Vendor() string
+	// Normalize parses a request body into one or more canonical reads.
+	Normalize(body []byte) ([]Read, error)
+}
+
+// FieldNamer is implemented by adapters that can name the vendor's own field behind a path in the normalized
+// (PlateSmart shaped) doc, so validation errors point at what the vendor actually sent.
+type FieldNamer interface {
+	VendorField(field string) (string, bool)
+}
+
+// vendorFields looks a normalized path up in a vendor's field names, the closest parent wins
+// (plate.region.x is whatever plate.region came from).
+func vendorFields(names map[string]string, field string) (string, bool) {
+	for f := field; f != ""; {
+		if name, ok := names[f]; ok {
+			return name, true
+		}
+		i := strings.LastIndex(f, ".")
+		if i < 0 {
+			break
+		}
+		f = f[:i]
+	}
+	return "", false
+}
+
+// vendorError renames a validation error's field for the adapter. one it can't name keeps the normalized
+// path, and says so.
+func vendorError(a Adapter, fe FieldError) FieldError {
+	if namer, ok := a.(FieldNamer); ok {
+		if name, ok := namer.VendorField(fe.Field); ok {
+			fe.Field = name
+			return fe
+		}
+	}
+	fe.Message += " (field of the normalized platesmart document)"
+	return fe
+}
+
+var adapters = map[string]Adapter{
+	"platesmart": PlateSmartAdapter{},
+	"genetec":    GenetecAdapter{},
+	"openalpr":   OpenALPRAdapter{},
+	"csv":        CSVAdapter{},
+}
+
+// AdapterFor returns the adapter registered for vendor. Empty means PlateSmart.
+func AdapterFor(vendor string) (Adapter, error) {
+	if vendor == "" {
+		vendor = DefaultVendor
+	}
+	a, ok := adapters[strings.ToLower(vendor)]
+	if !ok {
+		return nil, fmt.Errorf("unknown ingest vendor %q, expected one of %s", vendor, strings.Join(Vendors(), ", "))
+	}
+	return a, nil
+}
+
+func Vendors() []string {
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type Region struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Read is one plate read in vendor neutral form.
+type Read struct {
+	ReadID      string
+	Timestamp   time.Time
+	Plate       string
+	PlateCode   string //"US-NJ" style, same as PlateSmart
+	CameraID    string
+	CameraName  string
+	SourceType  string
+	ImageID     string
+	ImageWidth  int
+	ImageHeight int
+	PlateRegion *Region
+	Make        string
+	VehicleType string
+	Color       string
+	Occlusion   *float64
+	Latitude    *float64
+	Longitude   *float64
+	Vendor      string
+	Raw         json.RawMessage //the vendor's original record
+
+	doc []byte //set when the vendor doc is already in the stored layout (PlateSmart), used as is
+}
+
+// Doc renders the read as the document handed to alpr_util.ingest_alpr.
+func (r Read) Doc() ([]byte, error) {
+	if r.doc != nil {
+		return r.doc, nil
+	}
+
+	type code struct {
+		Code string `json:"code,omitempty"`
+		Name string `json:"name,omitempty"`
+	}
+	doc := map[string]any{
+		"id":        r.ReadID,
+		"type":      "alpr",
+		"timestamp": r.Timestamp.UnixMilli(),
+		"vendor":    r.Vendor,
+		"plate": map[string]any{
+			"tag":    r.Plate,
+			"code":   r.PlateCode,
+			"region": r.PlateRegion,
+		},
+		"source": map[string]any{
+			"id":   r.CameraID,
+			"name": r.CameraName,
+			"type": r.SourceType,
+		},
+	}
+
+	image := map[string]any{}
+	if r.ImageID != "" {
+		image["id"] = r.ImageID
+	}
+	if r.ImageWidth > 0 && r.ImageHeight > 0 {
+		image["width"], image["height"] = r.ImageWidth, r.ImageHeight
+	}
+	if len(image) > 0 {
+		doc["image"] = image
+	}
+
+	vehicle := map[string]any{}
+	if r.Make != "" {
+		vehicle["make"] = code{Code: strings.ToLower(r.Make), Name: r.Make}
+	}
+	if r.VehicleType != "" {
+		vehicle["type"] = code{Code: strings.ToLower(r.VehicleType), Name: r.VehicleType}
+	}
+	if r.Color != "" {
+		vehicle["color"] = code{Code: strings.ToLower(r.Color)}
+	}
+	if r.Occlusion != nil {
+		vehicle["occlusion"] = *r.Occlusion
+	}
+	if len(vehicle) > 0 {
+		doc["vehicle"] = vehicle
+	}
+
+	if r.Latitude != nil && r.Longitude != nil {
+		doc["location"] = map[string]float64{"latitude": *r.Latitude, "longitude": *r.Longitude}
+	}
+	if len(r.Raw) > 0 {
+		doc["raw"] = r.Raw
+	}
+
+	return json.Marshal(doc)
+}
+
+// normalize a 2 letter state or a "us-nj" region into PlateSmart's "US-NJ".
+func plateCode(region string) string {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	switch {
+	case region == "":
+		return ""
+	case len(region) == 2:
+		return "US-" + region
+	default:
+		return region
+	}
+}
+
+// epoch numbers in seconds or milliseconds, like alpr_util.parse_unixtime
+func fromEpoch(n int64) time.Time {
+	if n >= 1e12 {
+		return time.UnixMilli(n).UTC()
+	}
+	return time.Unix(n, 0).UTC()
+}
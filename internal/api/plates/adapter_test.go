@@ -0,0 +1,174 @@
+package plates
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// every adapter has to produce a doc the field map understands. Checked through ExtractFields,
+// which is what alpr_ingest_fill will do with it.
+func TestAdaptersProduceStorableDocs(t *testing.T) {
+	tests := []struct {
+		vendor string
+		body   string
+		want   []map[string]any
+	}{
+		{
+			vendor: "genetec",
+			body: `[{"ReadId":"g-1","Plate":"ABC123","PlateState":"nj","DateTimeUtc":"2025-03-01T14:05:06.5Z",
+				"CameraId":"cam-7","UnitName":"Patrol 12","Latitude":40.22,"Longitude":-74.76,
+				"VehicleMake":"Ford","VehicleColor":"White","VehicleType":"Pickup","ContextImageId":"img-9"}]`,
+			want: []map[string]any{{
+				"read_id": "g-1", "plate_num": "ABC123", "plate_code": "US-NJ", "camera_name": "Patrol 12",
+				"image_id": "img-9", "make": "Ford", "vehicle_type": "Pickup", "color": "white", "source_type": "autovu",
+			}},
+		},
+		{
+			vendor: "openalpr",
+			body: `{"data_type":"alpr_group","best_uuid":"u-1","epoch_start":1740837906000,"best_plate_number":"XYZ987",
+				"best_region":"us-pa","camera_id":42,"agent_uid":"agent-a","gps_latitude":40.1,"gps_longitude":-75.1,
+				"best_plate":{"img_width":1920,"img_height":1080,"coordinates":[{"x":10,"y":20},{"x":110,"y":22},{"x":108,"y":60},{"x":12,"y":58}]},
+				"vehicle":{"color":[{"name":"blue","confidence":80}],"make":[{"name":"honda","confidence":70}],"body_type":[{"name":"sedan","confidence":90}]},
+				"web_server_config":{"camera_label":"Main St EB"}}`,
+			want: []map[string]any{{
+				"read_id": "u-1", "plate_num": "XYZ987", "plate_code": "US-PA", "camera_name": "Main St EB",
+				"image_id": "u-1", "make": "honda", "vehicle_type": "sedan", "color": "blue",
+				"image_width": json.Number("1920"), "image_height": json.Number("1080"),
+				"plate_region": map[string]any{"x": json.Number("10"), "y": json.Number("20"), "width": json.Number("100"), "height": json.Number("40")},
+				"source_type":  "openalpr_agent:agent-a",
+			}},
+		},
+		{
+			vendor: "csv",
+			body:   "read_id,timestamp,plate,plate_code,camera_name,latitude,longitude,color\nc-1,2025-03-01T14:05:06Z,JKL555,NY,Gate 2,40.7,-74.0,red\nc-2,1740837906,JKL556,,Gate 2,,,\n",
+			want: []map[string]any{
+				{"read_id": "c-1", "plate_num": "JKL555", "plate_code": "US-NY", "camera_name": "Gate 2", "color": "red", "source_type": "csv"},
+				{"read_id": "c-2", "plate_num": "JKL556", "camera_name": "Gate 2", "source_type": "csv"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vendor, func(t *testing.T) {
+			adapter, err := AdapterFor(tt.vendor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reads, err := adapter.Normalize([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reads) != len(tt.want) {
+				t.Fatalf("got %d reads, want %d", len(reads), len(tt.want))
+			}
+			for i, r := range reads {
+				if r.Timestamp.IsZero() {
+					t.Errorf("read %d: no timestamp", i)
+				}
+				doc, err := r.Doc()
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := ExtractFields(doc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tt.want[i]) {
+					t.Errorf("read %d fields:\n got %v\nwant %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPlateSmartStoredAsReceived(t *testing.T) {
+	body := []byte(`{"id":"ps-1","timestamp":1722289388826,"plate":{"tag":"TEST1234","code":"US-NJ"},"source":{"name":"Parking Lot"},"extra":{"kept":true}}`)
+	reads, err := PlateSmartAdapter{}.Normalize(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := reads[0].Doc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(doc) != string(body) {
+		t.Errorf("platesmart doc was rewritten: %s", doc)
+	}
+	if reads[0].Timestamp.UnixMilli() != 1722289388826 {
+		t.Errorf("timestamp = %v", reads[0].Timestamp)
+	}
+}
+
+func TestAdapterErrors(t *testing.T) {
+	if _, err := AdapterFor("motorola"); err == nil {
+		t.Error("unknown vendor accepted")
+	}
+	if a, err := AdapterFor(""); err != nil || a.Vendor() != DefaultVendor {
+		t.Errorf("empty vendor = %v, %v", a, err)
+	}
+
+	bad := map[string]string{
+		"genetec":  `[{"ReadId":"g-1","DateTimeUtc":"yesterday"}]`,
+		"openalpr": `{"data_type":"heartbeat"}`,
+		"csv":      "plate,camera_name\nABC,Gate\n",
+	}
+	for vendor, body := range bad {
+		a, _ := AdapterFor(vendor)
+		if _, err := Ingest(t.Context(), a, []byte(body), nil); !errors.Is(err, ErrInvalidDocument) {
+			t.Errorf("%s: err = %v, want ErrInvalidDocument", vendor, err)
+		}
+	}
+}
+
+// field errors name what the vendor sent, not the normalized doc's path
+func TestIngestVendorFields(t *testing.T) {
+	a, _ := AdapterFor("genetec")
+	body := `[{"ReadId":"g-1","DateTimeUtc":"2025-03-01T14:05:00Z","Plate":"ABC123","Latitude":40.8,"Longitude":-74.3},
+		{"ReadId":"g-2","DateTimeUtc":"2025-03-01T14:05:00Z","Plate":" ","Latitude":95,"Longitude":-74.3}]`
+	_, err := Ingest(t.Context(), a, []byte(body), nil)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want a *ValidationError", err)
+	}
+	got := map[string]bool{}
+	for _, fe := range verr.Errors {
+		got[fe.Field] = true
+	}
+	if !got["reads[1].Plate"] || !got["reads[1].Latitude"] || len(got) != 2 {
+		t.Errorf("fields = %v", verr.Errors)
+	}
+
+	if fe := vendorError(a, FieldError{Field: "source.type", Message: "must be a string"}); fe.Field != "source.type" || !strings.Contains(fe.Message, "normalized") {
+		t.Errorf("unmapped field should say it's the normalized path: %+v", fe)
+	}
+	if fe := vendorError(OpenALPRAdapter{}, FieldError{Field: "plate.region.x"}); fe.Field != "best_plate.coordinates" {
+		t.Errorf("nested field should map through its parent: %+v", fe)
+	}
+}
+
+// a wrongly typed field gets the same per field errors as /add, not a decode error
+func TestPlateSmartWrongTypes(t *testing.T) {
+	body := `{"id":"ps-1","timestamp":1740837900000,"plate":{"tag":"ABC123"},"image":{"width":"wide"},
+		"location":{"latitude":"north","longitude":-74.3}}`
+	_, err := Ingest(t.Context(), PlateSmartAdapter{}, []byte(body), nil)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want a *ValidationError", err)
+	}
+	got := map[string]bool{}
+	for _, fe := range verr.Errors {
+		got[fe.Field] = true
+	}
+	if !got["image.width"] || !got["location.latitude"] {
+		t.Errorf("fields = %v", verr.Errors)
+	}
+
+	//type isn't validated, the decode error still names it
+	_, err = Ingest(t.Context(), PlateSmartAdapter{}, []byte(`{"type":7,"timestamp":1740837900000,"plate":{"tag":"ABC123"}}`), nil)
+	if !errors.As(err, &verr) || len(verr.Errors) != 1 || verr.Errors[0].Field != "type" {
+		t.Errorf("err = %v, want a type field error", err)
+	}
+}
@@ -0,0 +1,126 @@
+package plates
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// columns a generic csv export may carry. read_id, timestamp, plate and camera_name are required.
+// timestamp is RFC3339 or epoch seconds/milliseconds.
+var csvRequired = []string{"read_id", "timestamp", "plate", "camera_name"}
+
+// normalized doc path -> the column it's filled from
+var csvFields = map[string]string{
+	"id":                 "read_id",
+	"timestamp":          "timestamp",
+	"plate.tag":          "plate",
+	"plate.code":         "plate_code",
+	"source.id":          "camera_id",
+	"source.name":        "camera_name",
+	"image.id":           "image_id",
+	"vehicle.make":       "make",
+	"vehicle.type":       "vehicle_type",
+	"vehicle.color":      "color",
+	"location":           "latitude",
+	"location.longitude": "longitude",
+}
+
+type CSVAdapter struct{}
+
+func (CSVAdapter) Vendor() string { return "csv" }
+
+func (CSVAdapter) VendorField(field string) (string, bool) { return vendorFields(csvFields, field) }
+
+func (CSVAdapter) Normalize(body []byte) ([]Read, error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: reading header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, name := range csvRequired {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("invalid csv: missing required column %q", name)
+		}
+	}
+
+	var reads []Read
+	for line := 2; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		get := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		ts, err := parseCSVTime(get("timestamp"))
+		if err != nil {
+			return nil, fmt.Errorf("csv line %d: timestamp: %w", line, err)
+		}
+		read := Read{
+			ReadID:      get("read_id"),
+			Timestamp:   ts,
+			Plate:       get("plate"),
+			PlateCode:   plateCode(get("plate_code")),
+			CameraID:    get("camera_id"),
+			CameraName:  get("camera_name"),
+			SourceType:  "csv",
+			ImageID:     get("image_id"),
+			Make:        get("make"),
+			VehicleType: get("vehicle_type"),
+			Color:       get("color"),
+			Vendor:      "csv",
+		}
+		if read.Latitude, err = parseCSVFloat(get("latitude")); err != nil {
+			return nil, fmt.Errorf("csv line %d: latitude: %w", line, err)
+		}
+		if read.Longitude, err = parseCSVFloat(get("longitude")); err != nil {
+			return nil, fmt.Errorf("csv line %d: longitude: %w", line, err)
+		}
+		reads = append(reads, read)
+	}
+
+	if len(reads) == 0 {
+		return nil, fmt.Errorf("invalid csv: no rows")
+	}
+	return reads, nil
+}
+
+func parseCSVTime(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return fromEpoch(n), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+func parseCSVFloat(s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
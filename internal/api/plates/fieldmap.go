@@ -0,0 +1,86 @@
+package plates
+
+/* FieldMap is the Go copy of alpr_util.ingest_field_map, the table that tells
+alpr_ingest_fill which doc path fills which column. The db does the real extraction,
+this copy exists so the mapping can be tested against real PlateSmart documents
+(tools/plate_sender/plate_smart.json) without a database.
+
+Changing the mapping means adding a new version to the schema and bumping FieldMapVersion here.
+fieldmap_test.go fails if the two drift apart.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FieldMapVersion is the active (highest) version in alpr_util.ingest_field_map.
+const FieldMapVersion = 2
+
+type FieldMapping struct {
+	Column string
+	Path   []string
+}
+
+var FieldMap = []FieldMapping{
+	{Column: "plate_num", Path: []string{"plate", "tag"}},
+	{Column: "camera_name", Path: []string{"source", "name"}},
+	{Column: "plate_code", Path: []string{"plate", "code"}},
+	{Column: "image_id", Path: []string{"image", "id"}},
+	{Column: "read_id", Path: []string{"id"}},
+	{Column: "make", Path: []string{"vehicle", "make", "name"}},
+	{Column: "vehicle_type", Path: []string{"vehicle", "type", "name"}},
+	{Column: "color", Path: []string{"vehicle", "color", "code"}},
+	{Column: "image_width", Path: []string{"image", "width"}},
+	{Column: "image_height", Path: []string{"image", "height"}},
+	{Column: "plate_region", Path: []string{"plate", "region"}},
+	{Column: "occlusion", Path: []string{"vehicle", "occlusion"}},
+	{Column: "source_type", Path: []string{"source", "type"}},
+}
+
+// ExtractFields applies FieldMap to a doc the way alpr_ingest_fill does:
+// strings are trimmed of spaces (btrim), blank strings and nulls are left out, everything else is passed through as json.
+func ExtractFields(doc []byte) (map[string]any, error) {
+	var root any
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber() //keep numbers exactly as sent, the db casts them to the column type
+	if err := dec.Decode(&root); err != nil {
+		return nil, fmt.Errorf("doc is not valid json: %w", err)
+	}
+	if _, ok := root.(map[string]any); !ok {
+		return nil, fmt.Errorf("doc must be a JSON object")
+	}
+
+	fields := map[string]any{}
+	for _, m := range FieldMap {
+		val, ok := lookup(root, m.Path)
+		if !ok || val == nil {
+			continue
+		}
+		if s, isStr := val.(string); isStr {
+			s = strings.Trim(s, " ")
+			if s == "" {
+				continue
+			}
+			val = s
+		}
+		fields[m.Column] = val
+	}
+	return fields, nil
+}
+
+// lookup walks path through nested objects, like jsonb's #> operator.
+func lookup(v any, path []string) (any, bool) {
+	for _, key := range path {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,135 @@
+package plates
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Eyemetric/alpr_service/internal/migrate"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+const (
+	plateSmartSample = "../../../tools/plate_sender/plate_smart.json"
+	fieldMapGolden   = "testdata/plate_smart_fields.golden"
+)
+
+// TestExtractFieldsGolden runs every sample PlateSmart doc through the field map.
+// One line per doc so a mapping change shows up as a readable diff. Regenerate with go test -update.
+func TestExtractFieldsGolden(t *testing.T) {
+	raw, err := os.ReadFile(plateSmartSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var samples []struct {
+		Doc json.RawMessage `json:"doc"`
+	}
+	if err := json.Unmarshal(raw, &samples); err != nil {
+		t.Fatal(err)
+	}
+
+	var got bytes.Buffer
+	for i, s := range samples {
+		fields, err := ExtractFields(s.Doc)
+		if err != nil {
+			t.Fatalf("doc %d: %v", i, err)
+		}
+		//the color search filter depends on this, it was silently null before the v2 mapping
+		if fields["color"] == nil {
+			t.Errorf("doc %d: color not extracted", i)
+		}
+		line, _ := json.Marshal(fields) //map keys marshal sorted, so output is stable
+		got.Write(line)
+		got.WriteByte('\n')
+	}
+
+	if *update {
+		if err := os.WriteFile(fieldMapGolden, got.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(fieldMapGolden)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		gotLines := strings.Split(got.String(), "\n")
+		wantLines := strings.Split(string(want), "\n")
+		for i := 0; i < len(gotLines) && i < len(wantLines); i++ {
+			if gotLines[i] != wantLines[i] {
+				t.Fatalf("golden mismatch at doc %d:\n got: %s\nwant: %s", i, gotLines[i], wantLines[i])
+			}
+		}
+		t.Fatalf("golden mismatch: got %d lines, want %d", len(gotLines), len(wantLines))
+	}
+}
+
+func TestExtractFieldsBlankAndMissing(t *testing.T) {
+	doc := `{"id": "  abc ", "plate": {"tag": "   "}, "vehicle": {"color": null}}`
+	fields, err := ExtractFields([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields["read_id"] != "abc" {
+		t.Errorf("expected trimmed read_id, got %v", fields["read_id"])
+	}
+	for _, col := range []string{"plate_num", "color", "make"} {
+		if _, ok := fields[col]; ok {
+			t.Errorf("%s should be missing, got %v", col, fields[col])
+		}
+	}
+
+	if _, err := ExtractFields([]byte(`[1,2]`)); err == nil {
+		t.Error("expected non-object doc to be rejected")
+	}
+}
+
+// TestFieldMapMatchesSchema keeps FieldMap and the active version seeded by the migrations identical.
+func TestFieldMapMatchesSchema(t *testing.T) {
+	var schema strings.Builder
+	for _, m := range migrate.Embedded() {
+		schema.WriteString(m.Up)
+	}
+
+	//matches seed rows like: (2, 'color',        '{vehicle,color,code}'),
+	row := regexp.MustCompile(`^\s*\((\d+),\s*'(\w+)',\s*'\{([\w,]+)\}'\)`)
+	inSeed := false
+	schemaMap := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(schema.String()))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, "INSERT INTO alpr_util.ingest_field_map") {
+			inSeed = true
+			continue
+		}
+		if !inSeed {
+			continue
+		}
+		m := row.FindStringSubmatch(line)
+		if m == nil {
+			inSeed = false
+			continue
+		}
+		if v, _ := strconv.Atoi(m[1]); v == FieldMapVersion {
+			schemaMap[m[2]] = m[3]
+		}
+	}
+
+	goMap := map[string]string{}
+	for _, m := range FieldMap {
+		goMap[m.Column] = strings.Join(m.Path, ",")
+	}
+
+	if fmt.Sprint(goMap) != fmt.Sprint(schemaMap) {
+		t.Fatalf("FieldMap and schema version %d differ:\n  go:     %v\n  schema: %v", FieldMapVersion, goMap, schemaMap)
+	}
+}
@@ -0,0 +1,105 @@
+package plates
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// a read as exported by Genetec AutoVu (Security Center ALPR read event, JSON).
+// Images are fetched from Security Center by id, we only keep the context image id.
+type genetecRead struct {
+	ReadID         string   `json:"ReadId"`
+	Plate          string   `json:"Plate"`
+	PlateState     string   `json:"PlateState"`
+	DateTimeUtc    string   `json:"DateTimeUtc"`
+	CameraID       string   `json:"CameraId"`
+	CameraName     string   `json:"CameraName"`
+	UnitName       string   `json:"UnitName"`
+	Latitude       *float64 `json:"Latitude"`
+	Longitude      *float64 `json:"Longitude"`
+	VehicleMake    string   `json:"VehicleMake"`
+	VehicleType    string   `json:"VehicleType"`
+	VehicleColor   string   `json:"VehicleColor"`
+	ContextImageID string   `json:"ContextImageId"`
+}
+
+// normalized doc path -> the genetecRead field it's filled from
+var genetecFields = map[string]string{
+	"id":                 "ReadId",
+	"timestamp":          "DateTimeUtc",
+	"plate.tag":          "Plate",
+	"plate.code":         "PlateState",
+	"source.id":          "CameraId",
+	"source.name":        "CameraName",
+	"image.id":           "ContextImageId",
+	"vehicle.make":       "VehicleMake",
+	"vehicle.type":       "VehicleType",
+	"vehicle.color":      "VehicleColor",
+	"location":           "Latitude",
+	"location.longitude": "Longitude",
+}
+
+type GenetecAdapter struct{}
+
+func (GenetecAdapter) Vendor() string { return "genetec" }
+
+func (GenetecAdapter) VendorField(field string) (string, bool) {
+	return vendorFields(genetecFields, field)
+}
+
+// Normalize accepts a single read or an array of reads.
+func (GenetecAdapter) Normalize(body []byte) ([]Read, error) {
+	raws, err := splitJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid genetec document: %w", err)
+	}
+
+	reads := make([]Read, 0, len(raws))
+	for i, raw := range raws {
+		var g genetecRead
+		if err := json.Unmarshal(raw, &g); err != nil {
+			return nil, fmt.Errorf("genetec read %d: %w", i, err)
+		}
+		ts, err := time.Parse(time.RFC3339Nano, g.DateTimeUtc)
+		if err != nil {
+			return nil, fmt.Errorf("genetec read %d: DateTimeUtc: %w", i, err)
+		}
+
+		cameraName := g.CameraName
+		if cameraName == "" {
+			cameraName = g.UnitName
+		}
+		reads = append(reads, Read{
+			ReadID:      g.ReadID,
+			Timestamp:   ts.UTC(),
+			Plate:       g.Plate,
+			PlateCode:   plateCode(g.PlateState),
+			CameraID:    g.CameraID,
+			CameraName:  cameraName,
+			SourceType:  "autovu",
+			ImageID:     g.ContextImageID,
+			Make:        g.VehicleMake,
+			VehicleType: g.VehicleType,
+			Color:       g.VehicleColor,
+			Latitude:    g.Latitude,
+			Longitude:   g.Longitude,
+			Vendor:      "genetec",
+			Raw:         raw,
+		})
+	}
+	return reads, nil
+}
+
+// splitJSON returns each element of a json array, or the body itself if it's a single object.
+func splitJSON(body []byte) ([]json.RawMessage, error) {
+	var arr []json.RawMessage
+	if err := json.Unmarshal(body, &arr); err == nil {
+		return arr, nil
+	}
+	var obj json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, err
+	}
+	return []json.RawMessage{obj}, nil
+}
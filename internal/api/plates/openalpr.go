@@ -0,0 +1,134 @@
+package plates
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Rekor / OpenALPR agent webhook "alpr_group" payload. Only the fields we store are modeled.
+type openALPRGroup struct {
+	DataType        string   `json:"data_type"`
+	BestUUID        string   `json:"best_uuid"`
+	EpochStart      int64    `json:"epoch_start"`
+	BestPlateNumber string   `json:"best_plate_number"`
+	BestRegion      string   `json:"best_region"`
+	CameraID        int64    `json:"camera_id"`
+	AgentUID        string   `json:"agent_uid"`
+	Latitude        *float64 `json:"gps_latitude"`
+	Longitude       *float64 `json:"gps_longitude"`
+	BestPlate       *struct {
+		ImgWidth    int `json:"img_width"`
+		ImgHeight   int `json:"img_height"`
+		Coordinates []struct {
+			X int `json:"x"`
+			Y int `json:"y"`
+		} `json:"coordinates"`
+	} `json:"best_plate"`
+	Vehicle *struct {
+		Color    []openALPRGuess `json:"color"`
+		Make     []openALPRGuess `json:"make"`
+		BodyType []openALPRGuess `json:"body_type"`
+	} `json:"vehicle"`
+	WebServerConfig *struct {
+		CameraLabel string `json:"camera_label"`
+	} `json:"web_server_config"`
+}
+
+// candidates are sorted best first
+type openALPRGuess struct {
+	Name       string  `json:"name"`
+	Confidence float64 `json:"confidence"`
+}
+
+func best(guesses []openALPRGuess) string {
+	if len(guesses) == 0 {
+		return ""
+	}
+	return guesses[0].Name
+}
+
+// normalized doc path -> the alpr_group field it's filled from
+var openALPRFields = map[string]string{
+	"id":                 "best_uuid",
+	"timestamp":          "epoch_start",
+	"plate.tag":          "best_plate_number",
+	"plate.code":         "best_region",
+	"plate.region":       "best_plate.coordinates",
+	"source.id":          "camera_id",
+	"source.name":        "web_server_config.camera_label",
+	"source.type":        "agent_uid",
+	"image.id":           "best_uuid",
+	"image.width":        "best_plate.img_width",
+	"image.height":       "best_plate.img_height",
+	"vehicle.make":       "vehicle.make",
+	"vehicle.type":       "vehicle.body_type",
+	"vehicle.color":      "vehicle.color",
+	"location":           "gps_latitude",
+	"location.longitude": "gps_longitude",
+}
+
+type OpenALPRAdapter struct{}
+
+func (OpenALPRAdapter) Vendor() string { return "openalpr" }
+
+func (OpenALPRAdapter) VendorField(field string) (string, bool) {
+	return vendorFields(openALPRFields, field)
+}
+
+func (OpenALPRAdapter) Normalize(body []byte) ([]Read, error) {
+	var g openALPRGroup
+	if err := json.Unmarshal(body, &g); err != nil {
+		return nil, fmt.Errorf("invalid openalpr document: %w", err)
+	}
+	if g.DataType != "alpr_group" {
+		return nil, fmt.Errorf("unsupported openalpr data_type %q, only alpr_group is accepted", g.DataType)
+	}
+
+	cameraID := strconv.FormatInt(g.CameraID, 10)
+	cameraName := "camera " + cameraID
+	if g.WebServerConfig != nil && g.WebServerConfig.CameraLabel != "" {
+		cameraName = g.WebServerConfig.CameraLabel
+	}
+
+	r := Read{
+		ReadID:     g.BestUUID,
+		Timestamp:  fromEpoch(g.EpochStart),
+		Plate:      g.BestPlateNumber,
+		PlateCode:  plateCode(g.BestRegion),
+		CameraID:   cameraID,
+		CameraName: cameraName,
+		SourceType: "openalpr_agent:" + g.AgentUID,
+		ImageID:    g.BestUUID, //OpenALPR keys its images by the read uuid
+		Latitude:   g.Latitude,
+		Longitude:  g.Longitude,
+		Vendor:     "openalpr",
+		Raw:        body,
+	}
+
+	if bp := g.BestPlate; bp != nil {
+		r.ImageWidth, r.ImageHeight = bp.ImgWidth, bp.ImgHeight
+		r.PlateRegion = boundingBox(bp.Coordinates)
+	}
+	if v := g.Vehicle; v != nil {
+		r.Color, r.Make, r.VehicleType = best(v.Color), best(v.Make), best(v.BodyType)
+	}
+
+	return []Read{r}, nil
+}
+
+// OpenALPR gives the plate as polygon corners, we store a box.
+func boundingBox(pts []struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}) *Region {
+	if len(pts) == 0 {
+		return nil
+	}
+	minX, minY, maxX, maxY := pts[0].X, pts[0].Y, pts[0].X, pts[0].Y
+	for _, p := range pts[1:] {
+		minX, maxX = min(minX, p.X), max(maxX, p.X)
+		minY, maxY = min(minY, p.Y), max(maxY, p.Y)
+	}
+	return &Region{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
+}
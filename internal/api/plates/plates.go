@@ -2,18 +2,98 @@ package plates
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 
 	"github.com/Eyemetric/alpr_service/internal/repository"
 )
 
-func AddPlate(ctx context.Context, plate_doc []byte, repo repository.ALPRRepository) error {
+// AddPlate validates and stores a PlateSmart plate read. A doc that fails validation is returned as a *ValidationError
+// and never reaches the db. A read we've already stored comes back as a duplicate with the existing row id,
+// failures the db could not store go to the deadletter table and are reported in the result, not as an error.
+func AddPlate(ctx context.Context, plate_doc []byte, repo repository.ALPRRepository) (repository.IngestResult, error) {
+	if err := ValidatePlateSmart(plate_doc); err != nil {
+		return repository.IngestResult{}, err
+	}
+	return store(ctx, plate_doc, repo)
+}
+
+func store(ctx context.Context, plate_doc []byte, repo repository.ALPRRepository) (repository.IngestResult, error) {
 	res, err := repo.IngestPlateRead(ctx, plate_doc)
-	fmt.Printf("Result (empty ok): %s\n", res)
+	if err != nil {
+		return repository.IngestResult{}, err
+	}
+	return res, nil
+}
 
+// ErrInvalidDocument wraps adapter parse and validation failures, the caller sent something we can't store.
+var ErrInvalidDocument = errors.New("invalid document")
+
+type IngestReport struct {
+	Vendor  string                    `json:"vendor"`
+	Results []repository.IngestResult `json:"results"`
+}
+
+// Ingest normalizes a vendor's request body with its adapter and stores every read.
+// Every read is validated first, if any of them is invalid nothing is stored and the field errors name the
+// vendor's own field, prefixed with the read's position (reads[2].Latitude). After that each read is stored independently,
+// same as AddPlate, so one read failing in SQL dead-letters without failing the rest.
+func Ingest(ctx context.Context, adapter Adapter, body []byte, repo repository.ALPRRepository) (IngestReport, error) {
+	reads, err := adapter.Normalize(body)
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		for i, fe := range verr.Errors {
+			verr.Errors[i] = vendorError(adapter, fe)
+		}
+		return IngestReport{}, verr
+	}
 	if err != nil {
-		return err
+		return IngestReport{}, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
 	}
 
-	return nil
+	docs := make([][]byte, len(reads))
+	var invalid []FieldError
+	for i, read := range reads {
+		doc, err := read.Doc()
+		if err != nil {
+			return IngestReport{}, fmt.Errorf("read %s: %w", read.ReadID, err)
+		}
+		var verr *ValidationError
+		err = ValidatePlateSmart(doc)
+		if err != nil && !errors.As(err, &verr) {
+			return IngestReport{}, fmt.Errorf("read %s: %w", read.ReadID, err)
+		}
+		if verr != nil {
+			for _, fe := range verr.Errors {
+				fe = vendorError(adapter, fe)
+				if len(reads) > 1 {
+					fe.Field = fmt.Sprintf("reads[%d].%s", i, fe.Field)
+				}
+				invalid = append(invalid, fe)
+			}
+		}
+		docs[i] = doc
+	}
+	if len(invalid) > 0 {
+		return IngestReport{}, &ValidationError{Errors: invalid}
+	}
+
+	report := IngestReport{Vendor: adapter.Vendor(), Results: make([]repository.IngestResult, 0, len(docs))}
+	duplicates := 0
+	for _, doc := range docs {
+		res, err := store(ctx, doc, repo)
+		if err != nil {
+			return report, err
+		}
+		if res.Outcome == repository.IngestDuplicate {
+			duplicates++
+		}
+		report.Results = append(report.Results, res)
+	}
+	//once per batch, a replay can be all duplicates
+	if duplicates > 0 {
+		log.Printf("%s ingest: %d of %d reads were already stored\n", report.Vendor, duplicates, len(docs))
+	}
+	return report, nil
 }
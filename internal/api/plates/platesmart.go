@@ -0,0 +1,112 @@
+package plates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// the parts of a PlateSmart read we look at. The full doc is stored untouched.
+type plateSmartDoc struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Timestamp json.RawMessage `json:"timestamp"`
+	Image     *struct {
+		ID     string `json:"id"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+	} `json:"image"`
+	Plate *struct {
+		Tag    string  `json:"tag"`
+		Code   string  `json:"code"`
+		Region *Region `json:"region"`
+	} `json:"plate"`
+	Source *struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"source"`
+	Vehicle *struct {
+		Make      *codeName `json:"make"`
+		Type      *codeName `json:"type"`
+		Color     *codeName `json:"color"`
+		Occlusion *float64  `json:"occlusion"`
+	} `json:"vehicle"`
+	Location *struct {
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+	} `json:"location"`
+}
+
+type codeName struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type PlateSmartAdapter struct{}
+
+func (PlateSmartAdapter) Vendor() string { return "platesmart" }
+
+// the doc is already in the normalized layout
+func (PlateSmartAdapter) VendorField(field string) (string, bool) { return field, true }
+
+func (PlateSmartAdapter) Normalize(body []byte) ([]Read, error) {
+	var d plateSmartDoc
+	if err := json.Unmarshal(body, &d); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, typeError(body, typeErr)
+		}
+		return nil, fmt.Errorf("invalid platesmart document: %w", err)
+	}
+
+	r := Read{ReadID: d.ID, Vendor: "platesmart", doc: body}
+	var ms int64
+	if err := json.Unmarshal(d.Timestamp, &ms); err == nil {
+		r.Timestamp = fromEpoch(ms)
+	}
+	if d.Plate != nil {
+		r.Plate, r.PlateCode, r.PlateRegion = d.Plate.Tag, d.Plate.Code, d.Plate.Region
+	}
+	if d.Source != nil {
+		r.CameraID, r.CameraName, r.SourceType = d.Source.ID, d.Source.Name, d.Source.Type
+	}
+	if d.Image != nil {
+		r.ImageID, r.ImageWidth, r.ImageHeight = d.Image.ID, d.Image.Width, d.Image.Height
+	}
+	if v := d.Vehicle; v != nil {
+		if v.Make != nil {
+			r.Make = v.Make.Name
+		}
+		if v.Type != nil {
+			r.VehicleType = v.Type.Name
+		}
+		if v.Color != nil {
+			r.Color = v.Color.Code
+		}
+		r.Occlusion = v.Occlusion
+	}
+	if d.Location != nil {
+		r.Latitude, r.Longitude = d.Location.Latitude, d.Location.Longitude
+	}
+
+	return []Read{r}, nil
+}
+
+// typeError reports a field with the wrong type the way /add does, every problem the validator finds by field.
+// a field the validator doesn't check is reported from the decode error alone.
+func typeError(body []byte, err *json.UnmarshalTypeError) error {
+	var verr *ValidationError
+	if errors.As(ValidatePlateSmart(body), &verr) {
+		return verr
+	}
+	want := "a " + err.Type.Kind().String()
+	switch err.Type.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Float64:
+		want = "a number"
+	case reflect.Struct, reflect.Pointer:
+		want = "an object"
+	}
+	return &ValidationError{Errors: []FieldError{{Field: err.Field, Message: "must be " + want}}}
+}
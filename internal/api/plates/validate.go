@@ -0,0 +1,258 @@
+package plates
+
+/* Validation of PlateSmart documents before they reach the db.
+The staging table's CHECK constraints are still the last word, but a constraint failure only comes back
+as a sqlstate in the dead letter table. Checking the same rules here lets /add tell the caller exactly which field is wrong.
+Anything that passes here and still fails in SQL is dead-lettered like before.
+
+The rules follow what alpr_ingest_fill and the alpr_ingest constraints accept:
+  - timestamp: required epoch number (seconds, ms, us or ns, see alpr_util.parse_unixtime)
+  - plate.tag: required, not blank
+  - location: {latitude, longitude}, [lon, lat] or "lon,lat", in range
+  - every other mapped field is optional but must have the type its column expects
+
+A missing location is let through on purpose. The staging constraint still refuses it, so the read lands
+in the dead letter table and can be reprocessed once the camera's position is known, instead of being bounced
+back to a vendor that may not keep it.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every problem found in a document. errors.Is(err, ErrInvalidDocument) is true.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return "invalid document: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidDocument
+}
+
+// ValidatePlateSmart checks a doc against the rules above. Returns nil or a *ValidationError.
+func ValidatePlateSmart(doc []byte) error {
+	var root any
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	if err := dec.Decode(&root); err != nil {
+		return &ValidationError{Errors: []FieldError{{Field: "$", Message: "not valid json: " + err.Error()}}}
+	}
+	obj, ok := root.(map[string]any)
+	if !ok {
+		return &ValidationError{Errors: []FieldError{{Field: "$", Message: "must be a JSON object"}}}
+	}
+
+	v := validator{root: obj}
+
+	v.timestamp()
+	v.str("id", false)
+	v.str("plate.tag", true)
+	v.str("plate.code", false)
+	v.str("source.name", false)
+	v.str("source.type", false)
+	v.str("image.id", false)
+	v.str("vehicle.make.name", false)
+	v.str("vehicle.type.name", false)
+	v.str("vehicle.color.code", false)
+	v.integer("image.width", 0)
+	v.integer("image.height", 0)
+	v.region("plate.region")
+	v.number("vehicle.occlusion", 0, 1)
+	v.location()
+
+	if len(v.errs) > 0 {
+		return &ValidationError{Errors: v.errs}
+	}
+	return nil
+}
+
+type validator struct {
+	root map[string]any
+	errs []FieldError
+}
+
+func (v *validator) fail(field, format string, args ...any) {
+	fe := FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+	for _, seen := range v.errs {
+		if seen == fe {
+			return //a parent that isn't an object is hit once per child field
+		}
+	}
+	v.errs = append(v.errs, fe)
+}
+
+// get returns the value at a dotted path, nil if it or any parent is missing or null.
+// a parent that isn't an object is reported and treated as missing.
+func (v *validator) get(field string) any {
+	var cur any = v.root
+	path := strings.Split(field, ".")
+	for i, key := range path {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			if cur != nil {
+				v.fail(strings.Join(path[:i], "."), "must be an object")
+			}
+			return nil
+		}
+		cur = obj[key]
+	}
+	return cur
+}
+
+func (v *validator) str(field string, required bool) {
+	switch val := v.get(field).(type) {
+	case nil:
+		if required {
+			v.fail(field, "is required")
+		}
+	case string:
+		if required && strings.TrimSpace(val) == "" {
+			v.fail(field, "must not be blank")
+		}
+	default:
+		v.fail(field, "must be a string")
+	}
+}
+
+func (v *validator) num(field string) (float64, bool) {
+	switch val := v.get(field).(type) {
+	case nil:
+		return 0, false
+	case json.Number:
+		f, err := val.Float64()
+		if err != nil {
+			v.fail(field, "is not a usable number")
+			return 0, false
+		}
+		return f, true
+	default:
+		v.fail(field, "must be a number")
+		return 0, false
+	}
+}
+
+func (v *validator) number(field string, min, max float64) {
+	if f, ok := v.num(field); ok && (f < min || f > max) {
+		v.fail(field, "must be between %g and %g", min, max)
+	}
+}
+
+func (v *validator) integer(field string, min int64) {
+	val, ok := v.get(field).(json.Number)
+	if !ok {
+		v.num(field) //reports the wrong type, if any
+		return
+	}
+	n, err := strconv.ParseInt(val.String(), 10, 32)
+	if err != nil {
+		v.fail(field, "must be a whole number")
+		return
+	}
+	if n < min {
+		v.fail(field, "must be at least %d", min)
+	}
+}
+
+func (v *validator) region(field string) {
+	val := v.get(field)
+	if val == nil {
+		return
+	}
+	if _, ok := val.(map[string]any); !ok {
+		v.fail(field, "must be an object")
+		return
+	}
+	v.integer(field+".x", 0)
+	v.integer(field+".y", 0)
+	v.integer(field+".width", 0)
+	v.integer(field+".height", 0)
+}
+
+func (v *validator) timestamp() {
+	switch val := v.get("timestamp").(type) {
+	case nil:
+		v.fail("timestamp", "is required")
+	case json.Number:
+		n, err := val.Float64()
+		if err != nil || n <= 0 {
+			v.fail("timestamp", "must be a positive epoch time")
+		}
+	default:
+		v.fail("timestamp", "must be an epoch number (seconds or milliseconds)")
+	}
+}
+
+// same three shapes alpr_util.location_from_json accepts
+func (v *validator) location() {
+	var lon, lat float64
+	switch val := v.get("location").(type) {
+	case nil:
+		return
+	case map[string]any:
+		var okLon, okLat bool
+		lon, okLon = v.num("location.longitude")
+		lat, okLat = v.num("location.latitude")
+		if !okLon || !okLat {
+			if val["longitude"] == nil || val["latitude"] == nil {
+				v.fail("location", "must have latitude and longitude")
+			}
+			return
+		}
+	case []any:
+		if len(val) != 2 {
+			v.fail("location", "must be [longitude, latitude]")
+			return
+		}
+		var err1, err2 error
+		lon, err1 = jsonFloat(val[0])
+		lat, err2 = jsonFloat(val[1])
+		if err1 != nil || err2 != nil {
+			v.fail("location", "must be [longitude, latitude] numbers")
+			return
+		}
+	case string:
+		lonStr, latStr, found := strings.Cut(val, ",")
+		var err1, err2 error
+		lon, err1 = strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+		lat, err2 = strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+		if !found || err1 != nil || err2 != nil {
+			v.fail("location", `must be "longitude,latitude"`)
+			return
+		}
+	default:
+		v.fail("location", "must be an object, array or string")
+		return
+	}
+
+	if lat < -90 || lat > 90 {
+		v.fail("location.latitude", "must be between -90 and 90")
+	}
+	if lon < -180 || lon > 180 {
+		v.fail("location.longitude", "must be between -180 and 180")
+	}
+}
+
+func jsonFloat(val any) (float64, error) {
+	n, ok := val.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("not a number")
+	}
+	return n.Float64()
+}
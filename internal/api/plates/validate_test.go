@@ -0,0 +1,88 @@
+package plates
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSamplesAreValid(t *testing.T) {
+	raw, err := os.ReadFile(plateSmartSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var samples []struct {
+		Doc json.RawMessage `json:"doc"`
+	}
+	if err := json.Unmarshal(raw, &samples); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range samples {
+		if err := ValidatePlateSmart(s.Doc); err != nil {
+			t.Errorf("sample %d: %v", i, err)
+		}
+	}
+}
+
+func TestValidatePlateSmart(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []string //failing fields
+	}{
+		{"minimal", `{"timestamp":1722289388826,"plate":{"tag":"ABC"},"location":{"latitude":40,"longitude":-74}}`, nil},
+		{"array location", `{"timestamp":1722289388,"plate":{"tag":"ABC"},"location":[-74,40]}`, nil},
+		{"string location", `{"timestamp":1722289388,"plate":{"tag":"ABC"},"location":"-74, 40"}`, nil},
+		{"not an object", `[1,2]`, []string{"$"}},
+		{"not json", `{"timestamp":`, []string{"$"}},
+		{"empty", `{}`, []string{"timestamp", "plate.tag"}},
+		{"string timestamp", `{"timestamp":"2024-07-29T21:43:08Z","plate":{"tag":"ABC"},"location":[0,0]}`, []string{"timestamp"}},
+		{"blank tag", `{"timestamp":1,"plate":{"tag":"  "},"location":[0,0]}`, []string{"plate.tag"}},
+		{"plate not object", `{"timestamp":1,"plate":"ABC","location":[0,0]}`, []string{"plate", "plate.tag"}},
+		{"no location", `{"timestamp":1,"plate":{"tag":"ABC"}}`, nil}, //dead-lettered by the db instead
+		{"lat out of range", `{"timestamp":1,"plate":{"tag":"ABC"},"location":{"latitude":91,"longitude":-181}}`, []string{"location.latitude", "location.longitude"}},
+		{"missing longitude", `{"timestamp":1,"plate":{"tag":"ABC"},"location":{"latitude":40}}`, []string{"location"}},
+		{"wrong types", `{"id":7,"timestamp":1,"plate":{"tag":"ABC","region":{"x":-1,"y":2.5}},"location":[0,0],
+			"image":{"width":"800"},"vehicle":{"occlusion":3,"color":{"code":false}}}`,
+			[]string{"id", "vehicle.color.code", "image.width", "plate.region.x", "plate.region.y", "vehicle.occlusion"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePlateSmart([]byte(tt.doc))
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) || !errors.Is(err, ErrInvalidDocument) {
+				t.Fatalf("err = %v, want a ValidationError", err)
+			}
+			var got []string
+			for _, fe := range verr.Errors {
+				got = append(got, fe.Field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("failing fields = %v, want %v (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+// an invalid read in a batch rejects the whole batch before anything is stored, the nil repo would panic otherwise.
+func TestIngestValidatesEveryRead(t *testing.T) {
+	body := "read_id,timestamp,plate,camera_name,latitude,longitude\nc-1,1740837906,ABC,Gate,40.7,-74.0\nc-2,1740837906,DEF,Gate,95,-74.0\n"
+	_, err := Ingest(t.Context(), CSVAdapter{}, []byte(body), nil)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want a ValidationError", err)
+	}
+	if len(verr.Errors) != 1 || verr.Errors[0].Field != "reads[1].latitude" {
+		t.Errorf("errors = %+v", verr.Errors)
+	}
+}
@@ -0,0 +1,281 @@
+package retention
+
+/* Retention enforces how long each table keeps its rows.
+alpr is a hypertable in 7 day chunks, so old reads are removed a whole chunk at a time with drop_chunks.
+A chunk is only dropped once every read in it is past the cutoff, so reads can live up to a chunk longer than the policy.
+The other tables are plain tables and are trimmed with small batched deletes so a purge never holds
+long locks against ingest or the alert worker.
+
+Reads under a legal hold (internal/api/holds) are copied out of the hypertable right before chunks are dropped,
+in the same transaction and with legal_holds locked against new holds, so a hold created mid purge either gets
+captured or waits for the drop. If that copy fails nothing is dropped.
+
+Every scheduled run, dry or real, writes one retention_audit row per table. A dry run only counts what would go.
+Report counts the same way without writing anything, for the admin endpoint.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	MethodDropChunks  = "drop_chunks"
+	MethodBatchDelete = "batch_delete"
+
+	DefaultBatchSize = 5000
+)
+
+// DB is the part of pgxpool.Pool the purger needs.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Policy is how long one table keeps its rows. A zero MaxAge disables it.
+type Policy struct {
+	Table  string
+	Column string //the age of a row is measured on this column
+	Method string
+	MaxAge time.Duration
+	//extra condition for batch deletes, rows that don't match are never purged
+	Where string
+	//true when Column is timestamp without time zone (alpr.read_time), the cutoff is compared in UTC
+	LocalTime bool
+	//legal holds are captured before this table is purged
+	Held bool
+}
+
+// Durations per table, from config.
+type Durations struct {
+	ALPR       time.Duration
+	Alerts     time.Duration
+	Deadletter time.Duration
+	Events     time.Duration
+}
+
+// Policies maps the configured durations onto the tables they govern.
+func Policies(d Durations) []Policy {
+	return []Policy{
+		{Table: "alpr", Column: "read_time", Method: MethodDropChunks, MaxAge: d.ALPR, LocalTime: true, Held: true},
+		//only finished alerts, anything still pending or queued is kept no matter how old
+		{Table: "alerts", Column: "created_at", Method: MethodBatchDelete, MaxAge: d.Alerts, Where: "status in ('done','dead')"},
+		{Table: "alpr_deadletter", Column: "failed_at", Method: MethodBatchDelete, MaxAge: d.Deadletter},
+		{Table: "hotlist_alert_events", Column: "created_at", Method: MethodBatchDelete, MaxAge: d.Events},
+	}
+}
+
+// Result is what one policy did (or would do, on a dry run).
+type Result struct {
+	Table  string    `json:"table"`
+	Method string    `json:"method"`
+	Cutoff time.Time `json:"cutoff"`
+	DryRun bool      `json:"dry_run"`
+	Chunks int64     `json:"chunks,omitempty"` //drop_chunks only
+	Rows   int64     `json:"rows"`             //estimated for drop_chunks
+	Held   int64     `json:"held,omitempty"`   //reads newly copied to legal holds before the purge
+	Error  string    `json:"error,omitempty"`
+}
+
+type Purger struct {
+	DB        DB
+	Policies  []Policy
+	BatchSize int
+	//copies held reads older than before out of the way inside tx, returns how many were copied (repository CaptureLegalHolds)
+	Capture func(ctx context.Context, tx pgx.Tx, before time.Time) (int64, error)
+}
+
+// Run applies every enabled policy. One table failing doesn't stop the others, its error is in its Result.
+func (p *Purger) Run(ctx context.Context, dryRun bool) []Result {
+	return p.run(ctx, dryRun, true)
+}
+
+// Report is a dry run that leaves no audit rows behind, what the next purge would remove.
+func (p *Purger) Report(ctx context.Context) []Result {
+	return p.run(ctx, true, false)
+}
+
+func (p *Purger) run(ctx context.Context, dryRun, audit bool) []Result {
+	now := time.Now().UTC()
+	var results []Result
+
+	for _, pol := range p.Policies {
+		if pol.MaxAge <= 0 {
+			continue
+		}
+		started := time.Now()
+		res := Result{Table: pol.Table, Method: pol.Method, Cutoff: now.Add(-pol.MaxAge), DryRun: dryRun}
+
+		var err error
+		switch pol.Method {
+		case MethodDropChunks:
+			err = p.dropChunks(ctx, pol, &res)
+		case MethodBatchDelete:
+			err = p.batchDelete(ctx, pol, &res)
+		default:
+			err = fmt.Errorf("unknown retention method %q", pol.Method)
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+
+		if !audit {
+			results = append(results, res)
+			continue
+		}
+		if err := p.audit(ctx, res, started); err != nil {
+			log.Printf("retention: could not write audit record for %s: %v\n", pol.Table, err)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// cutoff as a sql expression on $1
+func (pol Policy) cutoffExpr() string {
+	if pol.LocalTime {
+		return "($1::timestamptz AT TIME ZONE 'UTC')"
+	}
+	return "$1::timestamptz"
+}
+
+func (p *Purger) dropChunks(ctx context.Context, pol Policy, res *Result) error {
+	table := pgx.Identifier{"public", pol.Table}.Sanitize()
+	cutoff := pol.cutoffExpr()
+
+	//counted first either way so the audit says how much went
+	err := p.DB.QueryRow(ctx, fmt.Sprintf(`
+		SELECT count(*), COALESCE(sum(approximate_row_count(c)), 0)::bigint
+		FROM show_chunks('%s', older_than => %s) c`, table, cutoff), res.Cutoff).Scan(&res.Chunks, &res.Rows)
+	if err != nil {
+		return fmt.Errorf("counting chunks: %w", err)
+	}
+	if res.DryRun || res.Chunks == 0 {
+		return nil
+	}
+
+	if pol.Held && p.Capture == nil {
+		return fmt.Errorf("%s is covered by legal holds but the purger has no capture, refusing to drop", pol.Table)
+	}
+
+	tx, err := p.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("starting purge: %w", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	if pol.Held {
+		//creating a hold inserts into legal_holds, share mode makes it wait until the chunks are gone.
+		//one committed before we got the lock is in the capture below.
+		if _, err := tx.Exec(ctx, "LOCK TABLE public.legal_holds IN SHARE MODE"); err != nil {
+			return fmt.Errorf("locking legal holds, nothing dropped: %w", err)
+		}
+		held, err := p.Capture(ctx, tx, res.Cutoff)
+		if err != nil {
+			return fmt.Errorf("capturing legal holds, nothing dropped: %w", err)
+		}
+		res.Held = held
+	}
+
+	var dropped int64
+	err = tx.QueryRow(ctx, fmt.Sprintf(`SELECT count(*) FROM drop_chunks('%s', older_than => %s)`, table, cutoff), res.Cutoff).Scan(&dropped)
+	if err != nil {
+		return fmt.Errorf("dropping chunks: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing purge: %w", err)
+	}
+	res.Chunks = dropped
+	return nil
+}
+
+func (p *Purger) batchDelete(ctx context.Context, pol Policy, res *Result) error {
+	table := pgx.Identifier{"public", pol.Table}.Sanitize()
+	where := fmt.Sprintf("%s < %s", pgx.Identifier{pol.Column}.Sanitize(), pol.cutoffExpr())
+	if pol.Where != "" {
+		where += " AND (" + pol.Where + ")"
+	}
+
+	if res.DryRun {
+		err := p.DB.QueryRow(ctx, fmt.Sprintf("SELECT count(*) FROM %s WHERE %s", table, where), res.Cutoff).Scan(&res.Rows)
+		if err != nil {
+			return fmt.Errorf("counting rows: %w", err)
+		}
+		return nil
+	}
+
+	batch := p.BatchSize
+	if batch <= 0 {
+		batch = DefaultBatchSize
+	}
+	query := fmt.Sprintf("DELETE FROM %[1]s WHERE id IN (SELECT id FROM %[1]s WHERE %[2]s LIMIT $2)", table, where)
+	for {
+		tag, err := p.DB.Exec(ctx, query, res.Cutoff, batch)
+		if err != nil {
+			return fmt.Errorf("deleting rows: %w", err)
+		}
+		res.Rows += tag.RowsAffected()
+		if tag.RowsAffected() < int64(batch) {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *Purger) audit(ctx context.Context, res Result, started time.Time) error {
+	details, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	_, err = p.DB.Exec(ctx, `
+		INSERT INTO public.retention_audit(table_name, method, cutoff, dry_run, chunks, rows_affected, error, started_at, details)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9)`,
+		res.Table, res.Method, res.Cutoff, res.DryRun, res.Chunks, res.Rows, res.Error, started, details)
+	return err
+}
+
+// Start runs Loop in the background.
+func (p *Purger) Start(ctx context.Context, interval time.Duration, dryRun bool) {
+	go p.Loop(ctx, interval, dryRun)
+}
+
+// Loop runs the purge every interval until ctx is done. The first run is one interval after it starts
+// so a restart loop can't hammer the db with purges.
+func (p *Purger) Loop(ctx context.Context, interval time.Duration, dryRun bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, res := range p.Run(ctx, dryRun) {
+				Log(res)
+			}
+		}
+	}
+}
+
+func Log(res Result) {
+	verb := "purged"
+	if res.DryRun {
+		verb = "would purge"
+	}
+	switch {
+	case res.Error != "":
+		log.Printf("retention: %s failed: %s\n", res.Table, res.Error)
+	case res.Method == MethodDropChunks:
+		log.Printf("retention: %s %d chunks (~%d rows) of %s older than %s\n", verb, res.Chunks, res.Rows, res.Table, res.Cutoff.Format(time.RFC3339))
+	default:
+		log.Printf("retention: %s %d rows of %s older than %s\n", verb, res.Rows, res.Table, res.Cutoff.Format(time.RFC3339))
+	}
+}
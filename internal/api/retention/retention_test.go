@@ -0,0 +1,164 @@
+package retention
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeDB records every statement. deletes report the next entry of deleted, counts return count.
+type fakeDB struct {
+	stmts   []string
+	deleted []int64
+	count   int64
+}
+
+func (f *fakeDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	f.stmts = append(f.stmts, sql)
+	if strings.Contains(sql, "DELETE") {
+		n := f.deleted[0]
+		f.deleted = f.deleted[1:]
+		return pgconn.NewCommandTag("DELETE " + strconv.FormatInt(n, 10)), nil
+	}
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (f *fakeDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	f.stmts = append(f.stmts, sql)
+	return fakeRow{f.count}
+}
+
+func (f *fakeDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	f.stmts = append(f.stmts, "BEGIN")
+	return &fakeTx{db: f}, nil
+}
+
+// fakeTx runs its statements through the fakeDB, they're recorded in the same order.
+type fakeTx struct {
+	pgx.Tx
+	db *fakeDB
+}
+
+func (t *fakeTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return t.db.Exec(ctx, sql, args...)
+}
+
+func (t *fakeTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return t.db.QueryRow(ctx, sql, args...)
+}
+
+func (t *fakeTx) Commit(ctx context.Context) error {
+	t.db.stmts = append(t.db.stmts, "COMMIT")
+	return nil
+}
+
+func (t *fakeTx) Rollback(ctx context.Context) error { return nil }
+
+type fakeRow struct{ n int64 }
+
+func (r fakeRow) Scan(dest ...any) error {
+	for _, d := range dest {
+		*d.(*int64) = r.n
+	}
+	return nil
+}
+
+func (f *fakeDB) ran(substr string) int {
+	n := 0
+	for _, s := range f.stmts {
+		if strings.Contains(s, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDryRunDeletesNothing(t *testing.T) {
+	db := &fakeDB{count: 3}
+	p := &Purger{DB: db, Policies: Policies(Durations{ALPR: 1000 * time.Hour, Deadletter: time.Hour})}
+
+	results := p.Run(context.Background(), true)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, disabled policies should be skipped: %+v", len(results), results)
+	}
+	if db.ran("DELETE") > 0 || db.ran("drop_chunks") > 0 {
+		t.Errorf("dry run modified data:\n%s", strings.Join(db.stmts, "\n"))
+	}
+	if got := db.ran("retention_audit"); got != 2 {
+		t.Errorf("wrote %d audit records, want 2", got)
+	}
+	if results[0].Chunks != 3 || results[1].Rows != 3 {
+		t.Errorf("dry run counts not reported: %+v", results)
+	}
+}
+
+func TestBatchDeleteLoopsUntilShortBatch(t *testing.T) {
+	db := &fakeDB{deleted: []int64{10, 10, 4}}
+	p := &Purger{DB: db, BatchSize: 10, Policies: Policies(Durations{Alerts: time.Hour})}
+
+	results := p.Run(context.Background(), false)
+	if len(results) != 1 || results[0].Rows != 24 || results[0].Error != "" {
+		t.Fatalf("results = %+v", results)
+	}
+	if got := db.ran("DELETE"); got != 3 {
+		t.Errorf("ran %d deletes, want 3", got)
+	}
+	//pending and queued alerts must survive any purge
+	if !strings.Contains(db.stmts[0], "status in ('done','dead')") {
+		t.Errorf("alerts delete isn't limited to finished alerts: %s", db.stmts[0])
+	}
+}
+
+func TestHoldsCapturedBeforeDrop(t *testing.T) {
+	db := &fakeDB{count: 2}
+	var captured time.Time
+	p := &Purger{DB: db, Policies: Policies(Durations{ALPR: 1000 * time.Hour})}
+
+	res := p.Run(context.Background(), false)
+	if res[0].Error == "" || db.ran("drop_chunks(") > 0 {
+		t.Fatalf("dropped chunks without capturing holds: %+v", res)
+	}
+
+	p.Capture = func(ctx context.Context, tx pgx.Tx, before time.Time) (int64, error) {
+		if db.ran("drop_chunks(") > 0 {
+			t.Error("chunks dropped before holds were captured")
+		}
+		if db.ran("BEGIN") != 1 || db.ran("LOCK TABLE public.legal_holds") != 1 {
+			t.Error("holds captured outside the locked purge transaction")
+		}
+		captured = before
+		return 5, nil
+	}
+	res = p.Run(context.Background(), false)
+	if res[0].Error != "" || res[0].Held != 5 || !captured.Equal(res[0].Cutoff) {
+		t.Errorf("result = %+v, captured before %v", res[0], captured)
+	}
+	if db.ran("drop_chunks(") != 1 {
+		t.Errorf("chunks not dropped")
+	}
+	//the capture and the drop commit together
+	if last := db.stmts[len(db.stmts)-2]; last != "COMMIT" {
+		t.Errorf("purge not committed before the audit:\n%s", strings.Join(db.stmts, "\n"))
+	}
+}
+
+// the admin report is read only, it mustn't fill retention_audit
+func TestReportWritesNothing(t *testing.T) {
+	db := &fakeDB{count: 3}
+	p := &Purger{DB: db, Policies: Policies(Durations{ALPR: 1000 * time.Hour, Deadletter: time.Hour})}
+
+	results := p.Report(context.Background())
+	if len(results) != 2 || !results[0].DryRun || results[0].Chunks != 3 {
+		t.Fatalf("results = %+v", results)
+	}
+	for _, write := range []string{"retention_audit", "DELETE", "drop_chunks", "BEGIN"} {
+		if db.ran(write) > 0 {
+			t.Errorf("report ran %s:\n%s", write, strings.Join(db.stmts, "\n"))
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Total is how many reads a search matches, counted or estimated.
+type Total struct {
+	Count    int64
+	Estimate bool
+}
+
+// BuildEstimateQuery is the search's matches without the count, the planner's row guess for it is the estimate.
+// count(*) can't be explained for that, its plan is an aggregate of 1 row.
+func BuildEstimateQuery(searchDoc SearchDoc) (*Query, error) {
+	qb := newQueryBuilder()
+	if err := qb.applyFilters(searchDoc); err != nil {
+		return nil, err
+	}
+	return &Query{Text: fmt.Sprintf("Select 1 from alpr %s", qb.whereClause()), Params: qb.args}, nil
+}
+
+// Count totals a search's matches. cheap ones are counted exactly, one the planner expects to match more than
+// ExactCountLimit reads or to cost more than MaxCost gets its estimate instead.
+// timescale's approximate_row_count is no help here, it only knows whole tables.
+func (l Limits) Count(ctx context.Context, tx pgx.Tx, searchDoc SearchDoc) (Total, error) {
+	eq, err := BuildEstimateQuery(searchDoc)
+	if err != nil {
+		return Total{}, err
+	}
+	est, err := Explain(ctx, tx, eq)
+	if err != nil {
+		return Total{}, err
+	}
+	if l.estimates(est) {
+		return Total{Count: int64(math.Round(est.Rows)), Estimate: true}, nil
+	}
+
+	cq, err := BuildCountQuery(searchDoc)
+	if err != nil {
+		return Total{}, err
+	}
+	var total Total
+	if err := tx.QueryRow(ctx, cq.Text, cq.Params...).Scan(&total.Count); err != nil {
+		return Total{}, fmt.Errorf("failed to count search: %w", err)
+	}
+	return total, nil
+}
+
+func (l Limits) estimates(est Estimate) bool {
+	return (l.ExactCountLimit > 0 && est.Rows > float64(l.ExactCountLimit)) || (l.MaxCost > 0 && est.Cost > l.MaxCost)
+}
+
+// NewMetadata fills in the paging for a total. a total of -1 isn't known, nor is the page count then.
+func NewMetadata(total Total, page, pageSize int) Metadata {
+	m := Metadata{TotalCount: total.Count, PageCount: -1, Page: page, PageSize: pageSize, IsEstimate: total.Estimate}
+	if total.Count >= 0 {
+		m.PageCount = int64(CalculateTotalPages(total.Count, pageSize))
+	}
+	return m
+}
@@ -0,0 +1,53 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewMetadata(t *testing.T) {
+	m := NewMetadata(Total{Count: 1001}, 1, 20)
+	if m.TotalCount != 1001 || m.PageCount != 51 || m.Page != 1 || m.PageSize != 20 || m.IsEstimate {
+		t.Errorf("metadata: %+v", m)
+	}
+	if m := NewMetadata(Total{Count: -1}, 3, 20); m.TotalCount != -1 || m.PageCount != -1 || m.Page != 3 {
+		t.Errorf("unknown total should leave the page count unknown: %+v", m)
+	}
+	if m := NewMetadata(Total{Count: 0}, 1, 20); m.PageCount != 0 {
+		t.Errorf("no matches is no pages: %+v", m)
+	}
+	if m := NewMetadata(Total{Count: 250_000, Estimate: true}, 1, 1000); m.PageCount != 250 || !m.IsEstimate {
+		t.Errorf("estimate: %+v", m)
+	}
+}
+
+func TestEstimates(t *testing.T) {
+	l := Limits{MaxCost: 1000, ExactCountLimit: 100}
+	tests := []struct {
+		est  Estimate
+		want bool
+	}{
+		{Estimate{Cost: 10, Rows: 50}, false},
+		{Estimate{Cost: 10, Rows: 101}, true},
+		{Estimate{Cost: 1001, Rows: 50}, true},
+	}
+	for _, tt := range tests {
+		if got := l.estimates(tt.est); got != tt.want {
+			t.Errorf("estimates(%+v) = %t, want %t", tt.est, got, tt.want)
+		}
+	}
+	if (Limits{}).estimates(Estimate{Cost: 1e12, Rows: 1e9}) {
+		t.Error("no limits should always count")
+	}
+}
+
+func TestBuildEstimateQuery(t *testing.T) {
+	doc := SearchDoc{StartDate: "2025-01-01T00:00:00", EndDate: "2025-01-02T00:00:00", PlateNum: "ABC%", Page: 2, PageSize: 20}
+	q, err := BuildEstimateQuery(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(q.Text, "count(") || strings.Contains(q.Text, "LIMIT") || len(q.Params) != 3 {
+		t.Errorf("query: %s %v", q.Text, q.Params)
+	}
+}
@@ -0,0 +1,309 @@
+package search
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// export formats
+const (
+	FormatCSV     = "csv"
+	FormatGeoJSON = "geojson"
+	FormatKML     = "kml"
+)
+
+// DefaultExportBatch is how many rows each FETCH pulls from the export cursor.
+const DefaultExportBatch = 1000
+
+// CursorIdleTimeout ends a cursor's transaction when nothing has been fetched for this long,
+// so a reader stuck on a slow client or a dead process doesn't hold the snapshot open.
+const CursorIdleTimeout = time.Minute
+
+// BuildExportQuery is BuildSelectQuery without the paging, every matching read in the same order.
+func BuildExportQuery(searchDoc SearchDoc) (*Query, error) {
+	if searchDoc.StartDate == "" || searchDoc.EndDate == "" {
+		return nil, fmt.Errorf("start_date and end_date are required")
+	}
+
+	qb := newQueryBuilder()
+	qb.nullLocation = true
+	if err := qb.applyFilters(searchDoc); err != nil {
+		return nil, err
+	}
+
+	q := Query{}
+	q.Text = fmt.Sprintf("%s %s %s", qb.selectSQL(), qb.whereClause(), qb.orderBy(searchDoc))
+	q.Params = qb.args
+	return &q, nil
+}
+
+// TxBeginner is what a cursor needs from the db, a *pgxpool.Pool.
+type TxBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// Cursor walks a query's results a batch at a time with a server side cursor,
+// so an export of millions of reads never holds more than a batch in memory and never pays for OFFSET.
+type Cursor struct {
+	tx    pgx.Tx
+	batch int
+	done  bool
+}
+
+// OpenCursor declares the cursor inside a read only transaction that ends itself after CursorIdleTimeout
+// without a fetch. Close has to be called to end it, ctx's deadline caps how long a fetch can run.
+func OpenCursor(ctx context.Context, db TxBeginner, q *Query, batch int) (*Cursor, error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+	return DeclareCursor(ctx, tx, q, batch)
+}
+
+// DeclareCursor is OpenCursor in a transaction that's already begun, one from Limits.Begin so the export
+// is held to the search guardrails. the cursor owns tx from then on, it's rolled back on an error or by Close.
+func DeclareCursor(ctx context.Context, tx pgx.Tx, q *Query, batch int) (*Cursor, error) {
+	if batch <= 0 {
+		batch = DefaultExportBatch
+	}
+	idle := fmt.Sprint(CursorIdleTimeout.Milliseconds())
+	if _, err := tx.Exec(ctx, "SELECT set_config('idle_in_transaction_session_timeout', $1, true)", idle); err != nil {
+		tx.Rollback(ctx)
+		return nil, fmt.Errorf("failed to set the export idle timeout: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "DECLARE alpr_export NO SCROLL CURSOR FOR "+q.Text, q.Params...); err != nil {
+		tx.Rollback(ctx)
+		return nil, fmt.Errorf("failed to declare export cursor: %w", err)
+	}
+	return &Cursor{tx: tx, batch: batch}, nil
+}
+
+// Next is the next batch of records, empty once the cursor is exhausted.
+func (c *Cursor) Next(ctx context.Context) ([]AlprRecord, error) {
+	if c.done {
+		return nil, nil
+	}
+	rows, err := c.tx.Query(ctx, fmt.Sprintf("FETCH FORWARD %d FROM alpr_export", c.batch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from export cursor: %w", err)
+	}
+	records, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[AlprRecord])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect export rows: %w", err)
+	}
+	c.done = len(records) < c.batch
+	return records, nil
+}
+
+// Close ends the transaction, which closes the cursor. nothing was written so it's a rollback.
+func (c *Cursor) Close(ctx context.Context) {
+	c.tx.Rollback(ctx)
+}
+
+// Exporter writes records in one export format. Begin before the first record, End after the last.
+type Exporter interface {
+	ContentType() string
+	Extension() string
+	Begin() error
+	Write(rec AlprRecord) error
+	End() error
+}
+
+// NewExporter returns the writer for format.
+func NewExporter(format string, w io.Writer) (Exporter, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV, "":
+		return &csvExporter{w: csv.NewWriter(w)}, nil
+	case FormatGeoJSON:
+		return &geoJSONExporter{w: w}, nil
+	case FormatKML:
+		return &kmlExporter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("format must be %s, %s or %s", FormatCSV, FormatGeoJSON, FormatKML)
+	}
+}
+
+type point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// point is the read's location, false for a read without one. exports leave those out rather than put them at 0,0.
+func (r AlprRecord) point() (point, bool) {
+	var p *point
+	if err := json.Unmarshal(r.Location, &p); err != nil || p == nil {
+		return point{}, false
+	}
+	return *p, true
+}
+
+// exportColumns are the columns of an export in order, every format uses the same ones.
+var exportColumns = []string{
+	"read_time", "plate_num", "plate_code", "camera_name", "make", "vehicle_type", "color",
+	"lat", "lon", "read_id", "image_id", "source_id", "score", "plate_img", "full_img",
+}
+
+func (r AlprRecord) exportRow() []string {
+	score, lat, lon := "", "", ""
+	if r.Score != nil {
+		score = strconv.FormatFloat(*r.Score, 'f', 4, 64)
+	}
+	if p, ok := r.point(); ok {
+		lat, lon = strconv.FormatFloat(p.Lat, 'f', -1, 64), strconv.FormatFloat(p.Lon, 'f', -1, 64)
+	}
+	return []string{
+		r.ReadTime.UTC().Format(time.RFC3339), str(r.PlateNum), str(r.PlateCode), str(r.CameraName),
+		str(r.Make), str(r.VehicleType), str(r.Color), lat, lon,
+		str(r.ReadID), str(r.ImageID), str(r.SourceID), score, r.PlateImg, r.FullImg,
+	}
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvExporter) ContentType() string { return "text/csv" }
+func (e *csvExporter) Extension() string   { return "csv" }
+
+func (e *csvExporter) Begin() error {
+	return e.w.Write(exportColumns)
+}
+
+func (e *csvExporter) Write(rec AlprRecord) error {
+	return e.w.Write(rec.exportRow())
+}
+
+func (e *csvExporter) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// geoJSONExporter writes a FeatureCollection, one Point feature per read with the columns as properties.
+// a read without a location gets a null geometry, which geojson allows for an unlocated feature.
+type geoJSONExporter struct {
+	w     io.Writer
+	wrote bool
+}
+
+func (e *geoJSONExporter) ContentType() string { return "application/geo+json" }
+func (e *geoJSONExporter) Extension() string   { return "geojson" }
+
+func (e *geoJSONExporter) Begin() error {
+	_, err := io.WriteString(e.w, `{"type":"FeatureCollection","features":[`)
+	return err
+}
+
+func (e *geoJSONExporter) Write(rec AlprRecord) error {
+	var geometry any
+	if p, ok := rec.point(); ok {
+		geometry = map[string]any{"type": "Point", "coordinates": []float64{p.Lon, p.Lat}}
+	}
+	props := map[string]any{}
+	for i, v := range rec.exportRow() {
+		switch col := exportColumns[i]; col {
+		case "lat", "lon":
+		case "score":
+			if rec.Score != nil {
+				props[col] = *rec.Score
+			}
+		default:
+			if v != "" {
+				props[col] = v
+			}
+		}
+	}
+	feature, err := json.Marshal(map[string]any{
+		"type":       "Feature",
+		"geometry":   geometry,
+		"properties": props,
+	})
+	if err != nil {
+		return err
+	}
+	if e.wrote {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.wrote = true
+	_, err = e.w.Write(feature)
+	return err
+}
+
+func (e *geoJSONExporter) End() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+// kmlExporter writes a Document with a Placemark per read, named for the plate and stamped with the read time
+// so Google Earth's time slider works. the columns go in ExtendedData, a read without a location has no Point.
+type kmlExporter struct {
+	w io.Writer
+}
+
+func (e *kmlExporter) ContentType() string { return "application/vnd.google-earth.kml+xml" }
+func (e *kmlExporter) Extension() string   { return "kml" }
+
+func (e *kmlExporter) Begin() error {
+	_, err := io.WriteString(e.w, xml.Header+`<kml xmlns="http://www.opengis.net/kml/2.2"><Document><name>ALPR search export</name>`+"\n")
+	return err
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPlacemark struct {
+	XMLName   xml.Name  `xml:"Placemark"`
+	Name      string    `xml:"name"`
+	TimeStamp string    `xml:"TimeStamp>when"`
+	Data      []kmlData `xml:"ExtendedData>Data"`
+	Point     *kmlPoint `xml:"Point"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+func (e *kmlExporter) Write(rec AlprRecord) error {
+	pm := kmlPlacemark{
+		Name:      str(rec.PlateNum),
+		TimeStamp: rec.ReadTime.UTC().Format(time.RFC3339),
+	}
+	if p, ok := rec.point(); ok {
+		pm.Point = &kmlPoint{Coordinates: fmt.Sprintf("%g,%g", p.Lon, p.Lat)}
+	}
+	for i, v := range rec.exportRow() {
+		if col := exportColumns[i]; v != "" && col != "lat" && col != "lon" {
+			pm.Data = append(pm.Data, kmlData{Name: col, Value: v})
+		}
+	}
+	b, err := xml.Marshal(pm)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(b, '\n'))
+	return err
+}
+
+func (e *kmlExporter) End() error {
+	_, err := io.WriteString(e.w, "</Document></kml>\n")
+	return err
+}
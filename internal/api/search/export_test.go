@@ -0,0 +1,167 @@
+package search
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportRecords() []AlprRecord {
+	plate, camera, color := "ABC123", `Rt 10 "West", Lane 1`, "Blue"
+	score := 0.5
+	return []AlprRecord{
+		{
+			PlateNum: &plate, CameraName: &camera, Color: &color, Score: &score,
+			ReadTime: time.Date(2025, 3, 1, 14, 5, 0, 0, time.UTC),
+			Location: json.RawMessage(`{"lat": 40.81234, "lon": -74.36512}`),
+			PlateImg: "https://example.com/plate?sig=1&x=2",
+		},
+		{ReadTime: time.Date(2025, 3, 1, 13, 0, 0, 0, time.UTC), Location: json.RawMessage(`null`)},
+	}
+}
+
+func export(t *testing.T, format string, records []AlprRecord) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	e, err := NewExporter(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		if err := e.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.End(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExportCSV(t *testing.T) {
+	rows, err := csv.NewReader(bytes.NewReader(export(t, FormatCSV, exportRecords()))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %d", len(rows))
+	}
+	row := map[string]string{}
+	for i, col := range rows[0] {
+		row[col] = rows[1][i]
+	}
+	if row["camera_name"] != `Rt 10 "West", Lane 1` || row["lat"] != "40.81234" || row["lon"] != "-74.36512" {
+		t.Errorf("row: %v", row)
+	}
+	if row["read_time"] != "2025-03-01T14:05:00Z" || row["score"] != "0.5000" {
+		t.Errorf("row: %v", row)
+	}
+	if rows[2][7] != "" || rows[2][8] != "" {
+		t.Errorf("a read without a location should have empty lat and lon: %v", rows[2])
+	}
+}
+
+func TestExportGeoJSON(t *testing.T) {
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry *struct {
+				Type        string    `json:"type"`
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(export(t, FormatGeoJSON, exportRecords()), &fc); err != nil {
+		t.Fatal(err)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 2 {
+		t.Fatalf("collection: %+v", fc)
+	}
+	f := fc.Features[0]
+	if f.Geometry.Type != "Point" || f.Geometry.Coordinates[0] != -74.36512 || f.Geometry.Coordinates[1] != 40.81234 {
+		t.Errorf("geometry should be [lon, lat]: %+v", f.Geometry)
+	}
+	if f.Properties["plate_num"] != "ABC123" || f.Properties["score"] != 0.5 {
+		t.Errorf("properties: %v", f.Properties)
+	}
+	if _, ok := fc.Features[1].Properties["plate_num"]; ok {
+		t.Error("missing values should be left out")
+	}
+	if fc.Features[1].Geometry != nil {
+		t.Errorf("a read without a location should have a null geometry: %+v", fc.Features[1].Geometry)
+	}
+
+	if err := json.Unmarshal(export(t, FormatGeoJSON, nil), &fc); err != nil || len(fc.Features) != 0 {
+		t.Errorf("empty export should be an empty collection: %v", err)
+	}
+}
+
+func TestExportKML(t *testing.T) {
+	out := export(t, FormatKML, exportRecords())
+	var doc struct {
+		Placemarks []struct {
+			Name        string `xml:"name"`
+			When        string `xml:"TimeStamp>when"`
+			Coordinates string `xml:"Point>coordinates"`
+			Data        []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:"value"`
+			} `xml:"ExtendedData>Data"`
+		} `xml:"Document>Placemark"`
+	}
+	if err := xml.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Placemarks) != 2 {
+		t.Fatalf("expected 2 placemarks, got %d", len(doc.Placemarks))
+	}
+	pm := doc.Placemarks[0]
+	if pm.Name != "ABC123" || pm.When != "2025-03-01T14:05:00Z" || pm.Coordinates != "-74.36512,40.81234" {
+		t.Errorf("placemark: %+v", pm)
+	}
+	if bytes.Count(out, []byte("<Point>")) != 1 {
+		t.Errorf("a read without a location shouldn't have a Point: %s", out)
+	}
+	for _, d := range pm.Data {
+		if d.Name == "plate_img" && d.Value != "https://example.com/plate?sig=1&x=2" {
+			t.Errorf("url should survive escaping: %q", d.Value)
+		}
+	}
+}
+
+func TestExportFormat(t *testing.T) {
+	if _, err := NewExporter("shapefile", &bytes.Buffer{}); err == nil {
+		t.Error("expected an unknown format to be refused")
+	}
+	if e, err := NewExporter("GeoJSON", &bytes.Buffer{}); err != nil || e.Extension() != "geojson" {
+		t.Errorf("format should be case-insensitive: %v", err)
+	}
+}
+
+func TestBuildExportQuery(t *testing.T) {
+	doc := SearchDoc{StartDate: "2025-01-01T00:00:00", EndDate: "2025-01-02T00:00:00", PlateNum: "ABC%", Page: 3, PageSize: 20}
+	q, err := BuildExportQuery(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(q.Text, "LIMIT") || strings.Contains(q.Text, "OFFSET") {
+		t.Errorf("export shouldn't page: %s", q.Text)
+	}
+	if !strings.Contains(q.Text, "ORDER BY read_time DESC") || len(q.Params) != 3 {
+		t.Errorf("query: %s %v", q.Text, q.Params)
+	}
+	if strings.Contains(q.Text, "'lat', 0.0") {
+		t.Errorf("export should select a missing location as null: %s", q.Text)
+	}
+	if _, err := BuildExportQuery(SearchDoc{}); err == nil {
+		t.Error("expected dates to be required")
+	}
+}
@@ -0,0 +1,127 @@
+package search
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// StringList takes a single string or an array, so "make": "Toyota" and "make": ["Toyota", "Honda"] both work.
+// blank entries are dropped.
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(b []byte) error {
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		var one string
+		if err := json.Unmarshal(b, &one); err != nil {
+			return err
+		}
+		list = []string{one}
+	}
+
+	*l = (*l)[:0]
+	for _, v := range list {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// Exclusions are reads to leave out. a read with no value for a field is never excluded by it.
+type Exclusions struct {
+	Make        StringList `json:"make"`
+	VehicleType StringList `json:"vehicle_type"`
+	Color       StringList `json:"color"`
+	PlateCode   StringList `json:"plate_code"`
+}
+
+// addAnyOf matches reads whose column is any of values, case-insensitive. the values go in as one array param.
+func (qb *queryBuilder) addAnyOf(column string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	qb.addCondition("LOWER("+column+") = ANY(%s)", lower(values))
+}
+
+// addNoneOf leaves out reads whose column is any of values, case-insensitive.
+func (qb *queryBuilder) addNoneOf(column string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	qb.addCondition("("+column+" IS NULL OR LOWER("+column+") <> ALL(%s))", lower(values))
+}
+
+// addLikeNone leaves out reads whose column matches any of the ilike patterns.
+func (qb *queryBuilder) addLikeNone(column string, patterns []string) {
+	if len(patterns) == 0 {
+		return
+	}
+	qb.addCondition("("+column+" IS NULL OR "+column+" NOT ILIKE ALL(%s))", patterns)
+}
+
+// addCameraNames matches reads from any of the cameras, case-insensitive. plain names are compared with
+// LOWER(camera_name) = ANY so idx_alpr_lower_camera_name serves them, only a name with % in it is an ilike pattern.
+func (qb *queryBuilder) addCameraNames(names []string) {
+	plain, patterns := cameraPatterns(names)
+	switch {
+	case len(plain) > 0 && len(patterns) > 0:
+		qb.addCondition("(LOWER(camera_name) = ANY(%s) OR camera_name ILIKE ANY(%s))", plain, patterns)
+	case len(plain) > 0:
+		qb.addCondition("LOWER(camera_name) = ANY(%s)", plain)
+	case len(patterns) > 0:
+		qb.addCondition("camera_name ILIKE ANY(%s)", patterns)
+	}
+}
+
+// addCameraNamesNone leaves out reads from any of the cameras, same rules as addCameraNames.
+func (qb *queryBuilder) addCameraNamesNone(names []string) {
+	plain, patterns := cameraPatterns(names)
+	switch {
+	case len(plain) > 0 && len(patterns) > 0:
+		qb.addCondition("(camera_name IS NULL OR (LOWER(camera_name) <> ALL(%s) AND camera_name NOT ILIKE ALL(%s)))", plain, patterns)
+	case len(plain) > 0:
+		qb.addCondition("(camera_name IS NULL OR LOWER(camera_name) <> ALL(%s))", plain)
+	case len(patterns) > 0:
+		qb.addCondition("(camera_name IS NULL OR camera_name NOT ILIKE ALL(%s))", patterns)
+	}
+}
+
+// cameraPatterns splits camera names into plain names, lowered, and % patterns. _ is an ordinary character
+// in both, real names like CAM_01 have it, so it's escaped in the patterns.
+func cameraPatterns(names []string) (plain, patterns []string) {
+	for _, n := range names {
+		if strings.Contains(n, "%") {
+			patterns = append(patterns, EscapeUnderscore(n))
+		} else {
+			plain = append(plain, strings.ToLower(n))
+		}
+	}
+	return plain, patterns
+}
+
+var underscoreEscaper = strings.NewReplacer(`\`, `\\`, "_", `\_`)
+
+// EscapeUnderscore makes _ (and the \ escape character itself) literal in an ilike pattern, leaving % a wildcard.
+func EscapeUnderscore(pattern string) string {
+	return underscoreEscaper.Replace(pattern)
+}
+
+// plateCodes turns 2 letter states into the stored form, platesmart prepends US-. anything else is ignored.
+func plateCodes(codes []string) []string {
+	var out []string
+	for _, c := range codes {
+		if len(c) == 2 {
+			out = append(out, "US-"+strings.ToUpper(c))
+		}
+	}
+	return out
+}
+
+func lower(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToLower(v)
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStringList(t *testing.T) {
+	var doc SearchDoc
+	err := json.Unmarshal([]byte(`{"make": "Toyota", "color": ["gray", " silver ", ""], "vehicle_type": ""}`), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]string(doc.Make), []string{"Toyota"}) {
+		t.Errorf("single value: %v", doc.Make)
+	}
+	if !reflect.DeepEqual([]string(doc.Color), []string{"gray", "silver"}) {
+		t.Errorf("list: %v", doc.Color)
+	}
+	if len(doc.VehicleType) != 0 {
+		t.Errorf("blank should be no filter: %v", doc.VehicleType)
+	}
+	if err := json.Unmarshal([]byte(`{"make": 4}`), &doc); err == nil {
+		t.Error("expected a number to be refused")
+	}
+}
+
+// "any gray or silver SUV not from NJ, and not the usual plates"
+func TestOrListsAndExclusions(t *testing.T) {
+	body := `{
+		"start_date": "2025-01-01T00:00:00", "end_date": "2025-01-02T00:00:00",
+		"plate_num": "%",
+		"color": ["gray", "Silver"],
+		"vehicle_type": "SUV",
+		"exclude_plates": ["ABC123", "XYZ%"],
+		"exclude_cameras": ["%test%"],
+		"not": {"plate_code": ["NJ"], "make": "Tesla"}
+	}`
+	var doc SearchDoc
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		t.Fatal(err)
+	}
+	q, err := BuildSelectQuery(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{
+		"read_time BETWEEN $1 AND $2",
+		"LOWER(vehicle_type) = ANY($3)",
+		"LOWER(color) = ANY($4)",
+		"(plate_num IS NULL OR plate_num NOT ILIKE ALL($5))",
+		"(camera_name IS NULL OR camera_name NOT ILIKE ALL($6))",
+		"(plate_code IS NULL OR LOWER(plate_code) <> ALL($7))",
+		"(make IS NULL OR LOWER(make) <> ALL($8))",
+		"plate_num ILIKE $9",
+	} {
+		if !strings.Contains(q.Text, want) {
+			t.Errorf("condition %d: expected %q in\n%s", i, want, q.Text)
+		}
+	}
+	if !reflect.DeepEqual(q.Params[3], []string{"gray", "silver"}) || !reflect.DeepEqual(q.Params[6], []string{"us-nj"}) {
+		t.Errorf("params not lowered or mapped: %v", q.Params)
+	}
+	if n := maxPlaceholder(q.Text); n != len(q.Params) {
+		t.Errorf("highest placeholder $%d but %d params", n, len(q.Params))
+	}
+}
+
+// plates keep plate_num's % and _ wildcards, cameras only take % like camera_names
+func TestExclusionWildcards(t *testing.T) {
+	doc := SearchDoc{StartDate: "2025-01-01T00:00:00", EndDate: "2025-01-02T00:00:00", PlateNum: "%",
+		ExcludePlates: []string{"AB_123"}, ExcludeCameras: []string{"CAM_01", "Route_10%"}}
+	q, err := BuildSelectQuery(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(q.Params[2], []string{"AB_123"}) {
+		t.Errorf("_ should stay a wildcard in an excluded plate, got %v", q.Params[2])
+	}
+	if !reflect.DeepEqual(q.Params[3], []string{"cam_01"}) || !reflect.DeepEqual(q.Params[4], []string{`Route\_10%`}) {
+		t.Errorf("_ should be literal in an excluded camera, got %v %v", q.Params[3], q.Params[4])
+	}
+}
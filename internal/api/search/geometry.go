@@ -0,0 +1,200 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// geometry types a search area can be. Polygon and MultiPolygon are GeoJSON,
+// Point needs a radius in meters, LineString a buffer in meters (a corridor along a road)
+// and BBox coordinates are [west, south, east, north].
+const (
+	GeoPolygon      = "Polygon"
+	GeoMultiPolygon = "MultiPolygon"
+	GeoPoint        = "Point"
+	GeoLineString   = "LineString"
+	GeoBBox         = "BBox"
+)
+
+const (
+	MaxVertices = 5000    //across all rings of a search area
+	MaxRadius   = 100_000 //meters
+	MaxBuffer   = 5_000   //meters either side of a LineString
+
+	//for sizing the && box. a degree of latitude on the spheroid ST_DWithin uses is 110,574-111,694 m and
+	//one of longitude at most 111,320 m * cos(lat), so 110 km errs on the big side for both
+	metersPerDegree = 110_000.0
+)
+
+// addGeometryFilter validates the search area and adds the condition for it.
+// every shape starts with a bounding box test (&& or ST_Intersects) so the location GiST index is used.
+func (qb *queryBuilder) addGeometryFilter(g *Geometry) error {
+	switch g.Type {
+	case GeoPolygon:
+		var rings [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &rings); err != nil {
+			return invalidFieldf("geometry.coordinates", "geometry.coordinates must be an array of rings for a Polygon: %w", err)
+		}
+		if err := checkPolygon(rings, new(int)); err != nil {
+			return err
+		}
+	case GeoMultiPolygon:
+		var polygons [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+			return invalidFieldf("geometry.coordinates", "geometry.coordinates must be an array of polygons for a MultiPolygon: %w", err)
+		}
+		if len(polygons) == 0 {
+			return invalidFieldf("geometry.coordinates", "geometry.coordinates must have at least one polygon")
+		}
+		vertices := 0
+		for _, rings := range polygons {
+			if err := checkPolygon(rings, &vertices); err != nil {
+				return err
+			}
+		}
+	case GeoPoint:
+		lon, lat, err := g.point()
+		if err != nil {
+			return err
+		}
+		if g.Radius <= 0 || g.Radius > MaxRadius {
+			return invalidFieldf("geometry.radius", "geometry.radius must be more than 0 and at most %d meters for a Point", MaxRadius)
+		}
+		center := fmt.Sprintf("ST_SetSRID(ST_MakePoint(%s, %s), 4326)", qb.nextPlaceholder(), qb.nextPlaceholder())
+		qb.args = append(qb.args, lon, lat)
+		qb.addWithin(center, math.Abs(lat), g.Radius)
+		return nil
+	case GeoLineString:
+		var line [][]float64
+		if err := json.Unmarshal(g.Coordinates, &line); err != nil {
+			return invalidFieldf("geometry.coordinates", "geometry.coordinates must be an array of positions for a LineString: %w", err)
+		}
+		maxLat, err := checkLine(line)
+		if err != nil {
+			return err
+		}
+		if g.Buffer <= 0 || g.Buffer > MaxBuffer {
+			return invalidFieldf("geometry.buffer", "geometry.buffer must be more than 0 and at most %d meters for a LineString", MaxBuffer)
+		}
+		geoJSON, err := g.geoJSON()
+		if err != nil {
+			return err
+		}
+		ph := qb.nextPlaceholder()
+		qb.args = append(qb.args, geoJSON)
+		qb.addWithin("ST_SetSRID(ST_GeomFromGeoJSON("+ph+"), 4326)", maxLat, g.Buffer)
+		return nil
+	case GeoBBox:
+		var box []float64
+		if err := json.Unmarshal(g.Coordinates, &box); err != nil || len(box) != 4 {
+			return invalidFieldf("geometry.coordinates", "geometry.coordinates must be [west, south, east, north] for a BBox")
+		}
+		if err := checkPosition(box[0], box[1]); err != nil {
+			return err
+		}
+		if err := checkPosition(box[2], box[3]); err != nil {
+			return err
+		}
+		if box[0] >= box[2] || box[1] >= box[3] {
+			return invalidFieldf("geometry.coordinates", "geometry.coordinates west must be less than east and south less than north")
+		}
+		qb.addCondition("location && ST_MakeEnvelope(%s, %s, %s, %s, 4326)", box[0], box[1], box[2], box[3])
+		return nil
+	default:
+		return invalidFieldf("geometry.type", "geometry.type must be %s, %s, %s, %s or %s", GeoPolygon, GeoMultiPolygon, GeoPoint, GeoLineString, GeoBBox)
+	}
+
+	geoJSON, err := g.geoJSON()
+	if err != nil {
+		return err
+	}
+	qb.addCondition("ST_Intersects(location, ST_SetSRID(ST_GeomFromGeoJSON(%s), 4326))", geoJSON)
+	return nil
+}
+
+// geoJSON is the shape as plain GeoJSON for postgis, without our extra members.
+func (g *Geometry) geoJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}{g.Type, g.Coordinates})
+}
+
+// addWithin matches reads within meters of shape, a sql geometry expression. the box test narrows with the index,
+// ST_DWithin on geography does the exact distance in meters. the box only has to be too big, never too small.
+// maxLat is the latitude furthest from the equator in the shape, the box reaches dLat past it,
+// where a degree of longitude is shorter still.
+func (qb *queryBuilder) addWithin(shape string, maxLat, meters float64) {
+	dLat := meters / metersPerDegree
+	edge := math.Min(maxLat+dLat, 90)
+	dLon := meters / (metersPerDegree * math.Max(math.Cos(edge*math.Pi/180), 0.01))
+	qb.addCondition("location && ST_Expand("+shape+", %s, %s)", dLon, dLat)
+	qb.addCondition("ST_DWithin(location::geography, "+shape+"::geography, %s)", meters)
+}
+
+func (g *Geometry) point() (lon, lat float64, err error) {
+	var pos []float64
+	if err := json.Unmarshal(g.Coordinates, &pos); err != nil || len(pos) != 2 {
+		return 0, 0, invalidFieldf("geometry.coordinates", "geometry.coordinates must be [longitude, latitude] for a Point")
+	}
+	return pos[0], pos[1], checkPosition(pos[0], pos[1])
+}
+
+// checkPolygon makes sure every ring is closed, has at least 4 positions and is in range.
+// vertices is the running count for the whole geometry.
+func checkPolygon(rings [][][]float64, vertices *int) error {
+	if len(rings) == 0 {
+		return invalidFieldf("geometry.coordinates", "geometry.coordinates has a polygon with no rings")
+	}
+	for _, ring := range rings {
+		if len(ring) < 4 {
+			return invalidFieldf("geometry.coordinates", "geometry.coordinates rings need at least 4 positions, got %d", len(ring))
+		}
+		for _, pos := range ring {
+			if len(pos) != 2 {
+				return invalidFieldf("geometry.coordinates", "geometry.coordinates positions must be [longitude, latitude]")
+			}
+			if err := checkPosition(pos[0], pos[1]); err != nil {
+				return err
+			}
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return invalidFieldf("geometry.coordinates", "geometry.coordinates rings must be closed, the first and last positions must be the same")
+		}
+		*vertices += len(ring)
+		if *vertices > MaxVertices {
+			return invalidFieldf("geometry", "geometry has more than %d vertices", MaxVertices)
+		}
+	}
+	return nil
+}
+
+// checkLine makes sure a LineString has at least 2 positions in range, returning the largest absolute latitude.
+func checkLine(line [][]float64) (float64, error) {
+	if len(line) < 2 {
+		return 0, invalidFieldf("geometry.coordinates", "geometry.coordinates needs at least 2 positions for a LineString")
+	}
+	if len(line) > MaxVertices {
+		return 0, invalidFieldf("geometry", "geometry has more than %d vertices", MaxVertices)
+	}
+	maxLat := 0.0
+	for _, pos := range line {
+		if len(pos) != 2 {
+			return 0, invalidFieldf("geometry.coordinates", "geometry.coordinates positions must be [longitude, latitude]")
+		}
+		if err := checkPosition(pos[0], pos[1]); err != nil {
+			return 0, err
+		}
+		maxLat = math.Max(maxLat, math.Abs(pos[1]))
+	}
+	return maxLat, nil
+}
+
+func checkPosition(lon, lat float64) error {
+	if lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+		return invalidFieldf("geometry.coordinates", "geometry.coordinates [%g, %g] is out of range, positions are [longitude, latitude]", lon, lat)
+	}
+	return nil
+}
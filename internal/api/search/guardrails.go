@@ -0,0 +1,120 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrTooBroad is a search refused because it would cost too much to run in a request.
+var ErrTooBroad = errors.New("search is too broad")
+
+// Limits are the guardrails a search run in a request is held to.
+type Limits struct {
+	StatementTimeout time.Duration
+	MaxCost          float64       //planner cost units, 0 for no check
+	MaxWildcardSpan  time.Duration //widest date range when plate_num matches every plate
+	ExactCountLimit  int64         //expected matches above which the total is estimated, 0 to always count
+}
+
+// CheckSpan refuses a search for every plate over more than MaxWildcardSpan.
+func (l Limits) CheckSpan(s SearchDoc) error {
+	if l.MaxWildcardSpan <= 0 || !everyPlate(s) {
+		return nil
+	}
+	start, end, err := s.DateRange()
+	if err != nil {
+		return fmt.Errorf("%w: plate_num %q matches every plate, it needs a valid start_date and end_date", ErrTooBroad, s.PlateNum)
+	}
+	if end.Sub(start) > l.MaxWildcardSpan {
+		return fmt.Errorf("%w: plate_num %q matches every plate, so the date range can be at most %s. narrow the dates, give part of a plate, or submit a search job",
+			ErrTooBroad, s.PlateNum, span(l.MaxWildcardSpan))
+	}
+	return nil
+}
+
+// everyPlate is a plate_num that filters nothing out. wildcards alone match every plate once there's a %,
+// _% and %__ only leave out plates shorter than the _s, which is close enough to every plate.
+// only the wildcard and ocr_confusable modes read them as wildcards, in exact mode "%" is just a plate.
+func everyPlate(s SearchDoc) bool {
+	if s.PlateNum == "" {
+		return true
+	}
+	if mode, err := s.plateMatch(); err == nil && mode != MatchWildcard && mode != MatchOCRConfusable {
+		return false
+	}
+	return strings.Trim(s.PlateNum, "%_") == "" && strings.Contains(s.PlateNum, "%")
+}
+
+// span reads a duration in days when it's whole days, 744h is 31 days.
+func span(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d days", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+// Estimate is the planner's guess at a query.
+type Estimate struct {
+	Cost float64 `json:"Total Cost"`
+	Rows float64 `json:"Plan Rows"`
+}
+
+// Explain asks the planner what q would cost without running it.
+func Explain(ctx context.Context, tx pgx.Tx, q *Query) (Estimate, error) {
+	var plan string
+	if err := tx.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+q.Text, q.Params...).Scan(&plan); err != nil {
+		return Estimate{}, fmt.Errorf("failed to explain search: %w", err)
+	}
+	var explained []struct {
+		Plan Estimate `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(plan), &explained); err != nil || len(explained) == 0 {
+		return Estimate{}, fmt.Errorf("failed to read search plan: %v", err)
+	}
+	return explained[0].Plan, nil
+}
+
+// CheckCost refuses a query the planner thinks costs more than MaxCost.
+func (l Limits) CheckCost(ctx context.Context, tx pgx.Tx, q *Query) (Estimate, error) {
+	if l.MaxCost <= 0 {
+		return Estimate{}, nil
+	}
+	est, err := Explain(ctx, tx, q)
+	if err != nil {
+		return est, err
+	}
+	if est.Cost > l.MaxCost {
+		return est, fmt.Errorf("%w: it would read about %.0f rows (cost %.0f, the limit is %.0f). narrow the dates, add cameras or an area, or submit a search job",
+			ErrTooBroad, est.Rows, est.Cost, l.MaxCost)
+	}
+	return est, nil
+}
+
+// Begin starts the read only transaction a search runs in, with statement_timeout set for it alone.
+func (l Limits) Begin(ctx context.Context, db TxBeginner) (pgx.Tx, error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin search transaction: %w", err)
+	}
+	if l.StatementTimeout > 0 {
+		ms := fmt.Sprint(l.StatementTimeout.Milliseconds())
+		if _, err := tx.Exec(ctx, "SELECT set_config('statement_timeout', $1, true)", ms); err != nil {
+			tx.Rollback(ctx)
+			return nil, fmt.Errorf("failed to set statement_timeout: %w", err)
+		}
+	}
+	return tx, nil
+}
+
+// TimedOut is true for a query stopped by statement_timeout.
+func TimedOut(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "57014" //query_canceled
+}
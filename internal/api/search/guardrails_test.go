@@ -0,0 +1,51 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckSpan(t *testing.T) {
+	limits := Limits{MaxWildcardSpan: 31 * 24 * time.Hour}
+	doc := func(plate, start, end string) SearchDoc {
+		return SearchDoc{PlateNum: plate, StartDate: start, EndDate: end}
+	}
+
+	tests := []struct {
+		name    string
+		doc     SearchDoc
+		tooWide bool
+	}{
+		{"every plate for a month", doc("%", "2025-01-01T00:00:00", "2025-02-01T00:00:00"), false},
+		{"every plate for a year", doc("%", "2025-01-01T00:00:00", "2026-01-01T00:00:00"), true},
+		{"repeated wildcard", doc("%%", "2025-01-01T00:00:00", "2026-01-01T00:00:00"), true},
+		{"no plate", doc("", "2025-01-01T00:00:00", "2026-01-01T00:00:00"), true},
+		{"every plate with bad dates", doc("%", "yesterday", "today"), true},
+		{"part of a plate for a year", doc("A%", "2025-01-01T00:00:00", "2026-01-01T00:00:00"), false},
+		{"one character wildcard", doc("_", "2025-01-01T00:00:00", "2026-01-01T00:00:00"), false},
+		{"wildcard after one character", doc("_%", "2025-01-01T00:00:00", "2026-01-01T00:00:00"), true},
+		{"wildcards around one character", doc("%_%", "2025-01-01T00:00:00", "2026-01-01T00:00:00"), true},
+		{"wildcard before two characters", doc("%__", "2025-01-01T00:00:00", "2026-01-01T00:00:00"), true},
+		{"only one character wildcards", doc("___", "2025-01-01T00:00:00", "2026-01-01T00:00:00"), false},
+		{"% as an exact plate", SearchDoc{PlateNum: "%", PlateMatch: MatchExact, StartDate: "2025-01-01T00:00:00", EndDate: "2026-01-01T00:00:00"}, false},
+		{"every plate ocr confusable", SearchDoc{PlateNum: "%", PlateMatch: MatchOCRConfusable, StartDate: "2025-01-01T00:00:00", EndDate: "2026-01-01T00:00:00"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := limits.CheckSpan(tt.doc)
+			if tt.tooWide != errors.Is(err, ErrTooBroad) {
+				t.Errorf("CheckSpan = %v, too wide %v", err, tt.tooWide)
+			}
+		})
+	}
+
+	err := limits.CheckSpan(doc("%", "2025-01-01T00:00:00", "2026-01-01T00:00:00"))
+	if !strings.Contains(err.Error(), "31 days") || !strings.Contains(err.Error(), "search job") {
+		t.Errorf("the error should say what's allowed and what to do: %v", err)
+	}
+	if err := (Limits{}).CheckSpan(doc("%", "2020-01-01T00:00:00", "2026-01-01T00:00:00")); err != nil {
+		t.Errorf("no span limit: %v", err)
+	}
+}
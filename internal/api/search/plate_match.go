@@ -0,0 +1,126 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// plate_match modes. with no mode plate_num is a wildcard pattern if it has % or _ and an exact plate otherwise.
+const (
+	MatchExact         = "exact"
+	MatchWildcard      = "wildcard"
+	MatchSimilar       = "similar"        //trigram similarity against plate_num, at least similarity
+	MatchOCRConfusable = "ocr_confusable" //characters OCR mixes up (0/O/D/Q, 1/I/L, ...) match each other
+)
+
+const (
+	// DefaultSimilarity is pg_trgm's own default threshold. a one character difference in a 7 character plate is ~0.4
+	DefaultSimilarity = 0.3
+
+	SortReadTime   = "read_time"
+	SortSimilarity = "similarity"
+)
+
+// confusables are the character groups plate OCR misreads for one another.
+// a plate character in a group matches any member of it in ocr_confusable mode.
+var confusables = []string{"0ODQ", "1IL", "2Z", "5S", "6G", "8B"}
+
+// plateMatch is the effective mode for the doc, checking the match fields along the way.
+func (s SearchDoc) plateMatch() (string, error) {
+	mode := strings.ToLower(s.PlateMatch)
+	switch mode {
+	case "":
+		if strings.ContainsAny(s.PlateNum, "%_") {
+			mode = MatchWildcard
+		} else {
+			mode = MatchExact
+		}
+	case MatchExact, MatchWildcard, MatchSimilar, MatchOCRConfusable:
+	default:
+		return "", invalidFieldf("plate_match", "plate_match must be one of %s, %s, %s or %s", MatchExact, MatchWildcard, MatchSimilar, MatchOCRConfusable)
+	}
+
+	if s.Similarity < 0 || s.Similarity > 1 {
+		return "", invalidFieldf("similarity", "similarity must be between 0 and 1")
+	}
+	if mode == MatchSimilar && strings.ContainsAny(s.PlateNum, "%_") {
+		return "", invalidFieldf("plate_num", "plate_num can't have wildcards when plate_match is %s", MatchSimilar)
+	}
+	if s.SortBy == SortSimilarity && !scored(mode) {
+		return "", invalidFieldf("sort_by", "sort_by %s needs plate_match %s or %s", SortSimilarity, MatchSimilar, MatchOCRConfusable)
+	}
+	if s.SortBy != "" && s.SortBy != SortReadTime && s.SortBy != SortSimilarity {
+		return "", invalidFieldf("sort_by", "sort_by must be %s or %s", SortReadTime, SortSimilarity)
+	}
+	return mode, nil
+}
+
+// scored modes return a similarity score with every record
+func scored(mode string) bool {
+	return mode == MatchSimilar || mode == MatchOCRConfusable
+}
+
+// addPlateFilter adds the plate_num condition for the mode and remembers the score expression for scored modes.
+func (qb *queryBuilder) addPlateFilter(plate, mode string, threshold float64) {
+	if plate == "" {
+		return
+	}
+
+	switch mode {
+	case MatchExact:
+		qb.addCondition("plate_num = %s", plate)
+	case MatchWildcard:
+		qb.addCondition("plate_num ILIKE %s", plate)
+	case MatchSimilar:
+		if threshold <= 0 {
+			threshold = DefaultSimilarity
+		}
+		ph := qb.nextPlaceholder()
+		qb.args = append(qb.args, plate)
+		// % is what the trigram index serves, it filters at the session threshold (0.3 unless changed),
+		// so a lower threshold has to go without it
+		if threshold >= DefaultSimilarity {
+			qb.conditions = append(qb.conditions, fmt.Sprintf("plate_num %% %s", ph))
+		}
+		qb.addCondition("similarity(plate_num, "+ph+") >= %s", threshold)
+		qb.score = fmt.Sprintf("similarity(plate_num, %s)::float8", ph)
+	case MatchOCRConfusable:
+		ph := qb.nextPlaceholder()
+		qb.args = append(qb.args, strings.NewReplacer("%", "", "_", "").Replace(plate))
+		//regex searches can use the trigram index too
+		qb.addCondition("plate_num ~* %s", confusableRegex(plate))
+		qb.score = fmt.Sprintf("similarity(plate_num, %s)::float8", ph)
+	}
+}
+
+// confusableRegex turns a plate (wildcards allowed) into an anchored regex where every character
+// matches its OCR look-alikes. "B0L%" becomes ^[8B][0ODQ][1IL].*$
+func confusableRegex(plate string) string {
+	var re strings.Builder
+	re.WriteString("^")
+	for _, r := range strings.ToUpper(plate) {
+		switch r {
+		case '%':
+			re.WriteString(".*")
+			continue
+		case '_':
+			re.WriteString(".")
+			continue
+		}
+		group := string(r)
+		for _, g := range confusables {
+			if strings.ContainsRune(g, r) {
+				group = g
+				break
+			}
+		}
+		if len(group) == 1 {
+			re.WriteString(regexp.QuoteMeta(group))
+		} else {
+			re.WriteString("[" + group + "]")
+		}
+	}
+	re.WriteString("$")
+	return re.String()
+}
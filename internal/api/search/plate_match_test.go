@@ -0,0 +1,92 @@
+package search
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestConfusableRegex(t *testing.T) {
+	cases := map[string]string{
+		"B0L%":   "^[8B][0ODQ][1IL].*$",
+		"abc123": "^A[8B]C[1IL][2Z]3$",
+		"X_9":    "^X.9$",
+	}
+	for plate, want := range cases {
+		if got := confusableRegex(plate); got != want {
+			t.Errorf("confusableRegex(%q) = %s, want %s", plate, got, want)
+		}
+	}
+}
+
+func TestPlateMatchModes(t *testing.T) {
+	base := SearchDoc{StartDate: "2025-01-01T00:00:00", EndDate: "2025-01-02T00:00:00"}
+
+	cases := []struct {
+		name  string
+		doc   func(d *SearchDoc)
+		where string
+		score bool
+	}{
+		{"legacy wildcard", func(d *SearchDoc) { d.PlateNum = "AB%" }, "plate_num ILIKE $3", false},
+		{"legacy exact", func(d *SearchDoc) { d.PlateNum = "ABC123" }, "plate_num = $3", false},
+		{"similar", func(d *SearchDoc) { d.PlateNum = "ABC123"; d.PlateMatch = MatchSimilar }, "plate_num % $3 AND similarity(plate_num, $3) >= $4", true},
+		{"similar below the index threshold", func(d *SearchDoc) { d.PlateNum = "ABC123"; d.PlateMatch = MatchSimilar; d.Similarity = 0.2 },
+			"read_time BETWEEN $1 AND $2 AND similarity(plate_num, $3) >= $4", true},
+		{"ocr", func(d *SearchDoc) { d.PlateNum = "AB0"; d.PlateMatch = MatchOCRConfusable }, "plate_num ~* $4", true},
+	}
+	for _, tc := range cases {
+		doc := base
+		tc.doc(&doc)
+		q, err := BuildSelectQuery(doc)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !strings.Contains(q.Text, tc.where) {
+			t.Errorf("%s: expected %q in\n%s", tc.name, tc.where, q.Text)
+		}
+		if got := strings.Contains(q.Text, "AS score"); got != tc.score {
+			t.Errorf("%s: score selected = %v, want %v", tc.name, got, tc.score)
+		}
+		if n := maxPlaceholder(q.Text); n != len(q.Params) {
+			t.Errorf("%s: highest placeholder $%d but %d params", tc.name, n, len(q.Params))
+		}
+	}
+}
+
+// placeholders can repeat (the scored modes use the plate in the where clause and the select list)
+// but every param needs one
+func maxPlaceholder(text string) int {
+	max := 0
+	for _, m := range regexp.MustCompile(`\$(\d+)`).FindAllStringSubmatch(text, -1) {
+		if n, _ := strconv.Atoi(m[1]); n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+func TestSortBySimilarity(t *testing.T) {
+	doc := SearchDoc{StartDate: "2025-01-01T00:00:00", EndDate: "2025-01-02T00:00:00", PlateNum: "ABC123",
+		PlateMatch: MatchSimilar, SortBy: SortSimilarity}
+	q, err := BuildSelectQuery(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(q.Text, "ORDER BY score DESC") {
+		t.Errorf("expected score ordering: %s", q.Text)
+	}
+
+	for _, bad := range []SearchDoc{
+		{PlateNum: "ABC", SortBy: SortSimilarity},
+		{PlateNum: "ABC", PlateMatch: "fuzzy"},
+		{PlateNum: "AB%", PlateMatch: MatchSimilar},
+		{PlateNum: "ABC", PlateMatch: MatchSimilar, Similarity: 1.5},
+	} {
+		bad.StartDate, bad.EndDate = doc.StartDate, doc.EndDate
+		if _, err := BuildSelectQuery(bad); err == nil {
+			t.Errorf("expected %+v to be refused", bad)
+		}
+	}
+}
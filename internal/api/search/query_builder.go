@@ -6,18 +6,18 @@ SearchDoc is a struct that represents the client's search request
 */
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
 	"math"
 	"strings"
 	"time"
 )
 
 type queryBuilder struct {
-	conditions []string
-	args       []any
-	phIndex    int //tracks next placeholder index ($1, $2, ...)
+	conditions   []string
+	args         []any
+	phIndex      int    //tracks next placeholder index ($1, $2, ...)
+	score        string //plate similarity expression for the scored plate_match modes, selected as score
+	nullLocation bool   //select a missing location as null instead of 0,0
 }
 
 func newQueryBuilder() *queryBuilder {
@@ -42,6 +42,15 @@ func parseDateTime(dateTimeStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("invalid date format for '%s': %w", dateTimeStr, err)
 }
 
+// DateRange is the parsed start_date and end_date.
+func (s SearchDoc) DateRange() (start, end time.Time, err error) {
+	if start, err = parseDateTime(s.StartDate); err != nil {
+		return start, end, err
+	}
+	end, err = parseDateTime(s.EndDate)
+	return start, end, err
+}
+
 // nextPlaceholder generates the next placeholder string (e.g., "$1") and increments the index.
 func (qb *queryBuilder) nextPlaceholder() string {
 	ph := fmt.Sprintf("$%d", qb.phIndex)
@@ -65,24 +74,6 @@ func (qb *queryBuilder) addCondition(fragment string, values ...any) {
 	qb.args = append(qb.args, values...)
 }
 
-// addInCondition is similar to addCondition but handles "field IN (...)" clauses specifically.
-func (qb *queryBuilder) addInCondition(field string, values []string) {
-	if len(values) == 0 {
-		return
-	}
-	// Type conversion needed as values are strings, but args needs interface{}
-	interfaceVals := make([]any, len(values))
-	placeholders := make([]string, len(values))
-	for i, v := range values {
-		placeholders[i] = qb.nextPlaceholder()
-		interfaceVals[i] = v // Store the original string value
-	}
-
-	condition := fmt.Sprintf("%s IN (%s)", field, strings.Join(placeholders, ", "))
-	qb.conditions = append(qb.conditions, condition)
-	qb.args = append(qb.args, interfaceVals...)
-}
-
 // whereClause constructs the final WHERE clause string.
 func (qb *queryBuilder) whereClause() string {
 	if len(qb.conditions) == 0 {
@@ -93,73 +84,77 @@ func (qb *queryBuilder) whereClause() string {
 
 // applyFilters adds all relevant filters to the queryBuilder based on SearchParams.
 // This function encapsulates the repetitive filtering logic.
-func (qb *queryBuilder) applyFilters(params SearchDoc) {
+func (qb *queryBuilder) applyFilters(params SearchDoc) error {
 
-	sd, errS := parseDateTime(params.StartDate)
-	ed, errE := parseDateTime(params.EndDate)
-	if errS == nil && errE == nil {
-		// Note: %s is used here because nextPlaceholder() returns the full "$N" string.
-		qb.addCondition("read_time BETWEEN %s AND %s", sd, ed)
-	} else {
-		log.Printf("Warning: Invalid date format provided, skipping date filter. StartErr: %v, EndErr: %v", errS, errE)
+	//a bad date used to drop the date filter, which turned a typo into a search of every read
+	sd, ed, err := params.DateRange()
+	if err != nil {
+		return err
 	}
+	// Note: %s is used here because nextPlaceholder() returns the full "$N" string.
+	qb.addCondition("read_time BETWEEN %s AND %s", sd, ed)
 
-	if params.Geometry != nil {
+	if err := qb.addRecurringFilter(params); err != nil {
+		return err
+	}
 
-		//NOTE: this should always work since we've already unmarshalled it, which would have failed and returned
-		//prior to this call,
-		//we should do something else because it's probably expensive to convert twice
-		geoJSON, err := json.Marshal(params.Geometry)
-		if err != nil {
-			fmt.Printf("error marshalling geometry: %v", err)
-		} else {
-			qb.addCondition("ST_Within(location, ST_SetSRID(ST_GeomFromGeoJSON(%s), 4326))", geoJSON)
+	if params.Geometry != nil {
+		if err := qb.addGeometryFilter(params.Geometry); err != nil {
+			return err
 		}
 	}
 
-	// Camera Names Filter (IN clause)
-	//NOTE: This currently requires full camera names which might be too restrictive
-	qb.addInCondition("camera_name", params.CameraNames)
+	// Camera Names Filter, case-insensitive. a name with % is a pattern
+	qb.addCameraNames(params.CameraNames)
 
 	// State Filter
-	if len(params.PlateCode) == 2 {
-		//plate code is a 2 char state but it's stored in the DB with US- prepended by platesmart
-		pcode := "US-" + strings.ToUpper(params.PlateCode)
-		qb.addCondition("plate_code = %s", pcode)
-	}
+	qb.addAnyOf("plate_code", plateCodes(params.PlateCode))
 
-	// Vehicle Make Filter
-	if len(params.Make) > 1 {
-		qb.addCondition("LOWER(make) = LOWER(%s)", params.Make)
-	}
+	// Vehicle Make, Type and Color Filters, any of the listed values
+	qb.addAnyOf("make", params.Make)
+	qb.addAnyOf("vehicle_type", params.VehicleType)
+	qb.addAnyOf("color", params.Color)
 
-	// Vehicle Type Filter
-	if len(params.VehicleType) > 1 {
-		qb.addCondition("LOWER(vehicle_type) = LOWER(%s)", params.VehicleType)
-	}
-
-	// Vehicle Color Filter
-	if len(params.Color) > 1 {
-		qb.addCondition("LOWER(color) = LOWER(%s)", params.Color)
+	// Exclusions
+	qb.addLikeNone("plate_num", params.ExcludePlates)
+	qb.addCameraNamesNone(params.ExcludeCameras)
+	if not := params.Not; not != nil {
+		qb.addNoneOf("plate_code", plateCodes(not.PlateCode))
+		qb.addNoneOf("make", not.Make)
+		qb.addNoneOf("vehicle_type", not.VehicleType)
+		qb.addNoneOf("color", not.Color)
 	}
 
 	// Plate Num Filter
-	if params.PlateNum != "" {
-		if strings.ContainsAny(params.PlateNum, "%_") {
-			qb.addCondition("plate_num ILIKE %s", params.PlateNum)
-		} else {
-			qb.addCondition("plate_num = %s", params.PlateNum)
-		}
+	mode, err := params.plateMatch()
+	if err != nil {
+		return err
 	}
+	qb.addPlateFilter(params.PlateNum, mode, params.Similarity)
+	return nil
 }
 
 // Base select statement.
 // NOTE: location is returned as a jsonb fragment so we don't need special golang GeomTypes, easier
-const baseSQL = `
+const baseColumns = `
 	    SELECT plate_num, plate_code, camera_name, read_id, read_time, image_id, make, vehicle_type, color,
 	    CASE WHEN location IS NOT NULL THEN jsonb_build_object('lat', TRUNC(ST_Y(location)::numeric, 5), 'lon', TRUNC(ST_X(location)::numeric, 5))
-	    ELSE jsonb_build_object('lat', 0.0, 'lon', 0.0)
-	    END AS location, doc->'source'->>'id' as source_id FROM alpr`
+	    %s
+	    END AS location, doc->'source'->>'id' as source_id`
+
+// search pages keep answering 0,0 for a read without a location, exports get null so it isn't mapped there.
+const zeroLocation = "ELSE jsonb_build_object('lat', 0.0, 'lon', 0.0)"
+
+func (qb *queryBuilder) selectSQL() string {
+	columns := fmt.Sprintf(baseColumns, zeroLocation)
+	if qb.nullLocation {
+		columns = fmt.Sprintf(baseColumns, "")
+	}
+	if qb.score != "" {
+		return columns + ", " + qb.score + " AS score FROM alpr"
+	}
+	return columns + " FROM alpr"
+}
 
 // NOTE: this is limit offset style paging which may inhibit performance as the db size grows. The alternative is next_page tokens.
 // which is faster but more limited in that only the next or previous page can be retrieved whereas limit/offset allows jumping to any page directly
@@ -177,8 +172,15 @@ func (qb *queryBuilder) addPagination(searchDoc SearchDoc) string {
 	limitPh := qb.nextPlaceholder()
 	offsetPh := qb.nextPlaceholder()
 	qb.args = append(qb.args, pageSize, offset)
-	order := " ORDER BY read_time DESC, id DESC"
-	return fmt.Sprintf("%s LIMIT %s OFFSET %s", order, limitPh, offsetPh)
+	return fmt.Sprintf("%s LIMIT %s OFFSET %s", qb.orderBy(searchDoc), limitPh, offsetPh)
+}
+
+// orderBy is newest first, or best match first when sorting by similarity.
+func (qb *queryBuilder) orderBy(searchDoc SearchDoc) string {
+	if searchDoc.SortBy == SortSimilarity && qb.score != "" {
+		return " ORDER BY score DESC, read_time DESC, id DESC"
+	}
+	return " ORDER BY read_time DESC, id DESC"
 }
 
 //Query struct
@@ -197,10 +199,12 @@ func BuildSelectQuery(searchDoc SearchDoc) (*Query, error) {
 	}
 
 	qb := newQueryBuilder()
-	qb.applyFilters(searchDoc) // Use the shared filter logic
+	if err := qb.applyFilters(searchDoc); err != nil { // Use the shared filter logic
+		return nil, err
+	}
 
 	q := Query{}
-	q.Text = fmt.Sprintf("%s %s %s", baseSQL, qb.whereClause(), qb.addPagination(searchDoc))
+	q.Text = fmt.Sprintf("%s %s %s", qb.selectSQL(), qb.whereClause(), qb.addPagination(searchDoc))
 	q.Params = qb.args
 
 	return &q, nil
@@ -209,11 +213,12 @@ func BuildSelectQuery(searchDoc SearchDoc) (*Query, error) {
 // constructs the COUNT query using the internal builder.
 // We need to get the total row count for a search so that we now how to divide the pages.
 func BuildCountQuery(searchDoc SearchDoc) (*Query, error) {
-	// No need to validate required fields strictly for count filters,
-	// applyFilters will handle missing ones gracefully.
+	// the doc has been through BuildSelectQuery already, applyFilters still refuses bad dates.
 
 	qb := newQueryBuilder()
-	qb.applyFilters(searchDoc) // Use the shared filter logic
+	if err := qb.applyFilters(searchDoc); err != nil { // Use the shared filter logic
+		return nil, err
+	}
 
 	q := Query{}
 	q.Text = fmt.Sprintf("Select count(*) from alpr %s", qb.whereClause())
@@ -3,6 +3,8 @@ package search
 import (
 	"encoding/json"
 	"log"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -64,5 +66,99 @@ func TestParseDateTime(t *testing.T) {
 }
 
 func TestFilterGeo(t *testing.T) {
+	good := map[string][]string{
+		`{"type": "Polygon", "coordinates": [[[-74.5, 40.5], [-74.3, 40.5], [-74.3, 40.7], [-74.5, 40.5]]]}`: {"ST_Intersects(location"},
+		`{"type": "MultiPolygon", "coordinates": [[[[-74.5, 40.5], [-74.3, 40.5], [-74.3, 40.7], [-74.5, 40.5]]],
+		  [[[-75.5, 40.5], [-75.3, 40.5], [-75.3, 40.7], [-75.5, 40.5]]]]}`: {"ST_Intersects(location"},
+		`{"type": "Point", "coordinates": [-74.4, 40.6], "radius": 500}`: {"location && ST_Expand(", "ST_DWithin(location::geography"},
+		`{"type": "BBox", "coordinates": [-74.5, 40.5, -74.3, 40.7]}`:    {"location && ST_MakeEnvelope"},
+		`{"type": "LineString", "coordinates": [[-74.5, 40.8], [-74.3, 40.85]], "buffer": 100}`: {
+			"location && ST_Expand(ST_SetSRID(ST_GeomFromGeoJSON($1), 4326), $2, $3)",
+			"ST_DWithin(location::geography, ST_SetSRID(ST_GeomFromGeoJSON($1), 4326)::geography, $4)",
+		},
+	}
+	for geo, want := range good {
+		qb := newQueryBuilder()
+		var g Geometry
+		if err := json.Unmarshal([]byte(geo), &g); err != nil {
+			t.Fatal(err)
+		}
+		if err := qb.addGeometryFilter(&g); err != nil {
+			t.Errorf("%s: %v", g.Type, err)
+			continue
+		}
+		where := qb.whereClause()
+		for _, w := range want {
+			if !strings.Contains(where, w) {
+				t.Errorf("%s: expected %q in %s", g.Type, w, where)
+			}
+		}
+		if qb.phIndex-1 != len(qb.args) {
+			t.Errorf("%s: %d placeholders for %d args", g.Type, qb.phIndex-1, len(qb.args))
+		}
+	}
+
+	bad := []string{
+		`{"type": "Polygon", "coordinates": [[[-74.5, 40.5], [-74.3, 40.5], [-74.3, 40.7], [-74.4, 40.7]]]}`,   //not closed
+		`{"type": "Polygon", "coordinates": [[[-74.5, 40.5], [-74.3, 40.5], [-74.5, 40.5]]]}`,                  //too few positions
+		`{"type": "Polygon", "coordinates": [[[40.5, -174.5], [40.5, -74.3], [40.7, -74.3], [40.5, -174.5]]]}`, //lat/lon swapped
+		`{"type": "Point", "coordinates": [-74.4, 40.6]}`,                                                      //no radius
+		`{"type": "Point", "coordinates": [-74.4, 40.6], "radius": 1000000}`,
+		`{"type": "BBox", "coordinates": [-74.3, 40.5, -74.5, 40.7]}`, //west > east
+		`{"type": "Circle", "coordinates": [-74.4, 40.6]}`,
+		`{"type": "LineString", "coordinates": [[-74.5, 40.8], [-74.3, 40.85]]}`,                  //no buffer
+		`{"type": "LineString", "coordinates": [[-74.5, 40.8]], "buffer": 100}`,                   //one position
+		`{"type": "LineString", "coordinates": [[-74.5, 40.8], [-74.3, 40.85]], "buffer": 50000}`, //wider than a corridor
+	}
+	for _, geo := range bad {
+		var g Geometry
+		if err := json.Unmarshal([]byte(geo), &g); err != nil {
+			t.Fatal(err)
+		}
+		if err := newQueryBuilder().addGeometryFilter(&g); err == nil {
+			t.Errorf("expected %s to be refused", geo)
+		}
+	}
+
+	var ring strings.Builder
+	ring.WriteString(`{"type": "Polygon", "coordinates": [[`)
+	for i := 0; i < MaxVertices; i++ {
+		ring.WriteString(`[-74.5, 40.5], `)
+	}
+	ring.WriteString(`[-74.5, 40.5]]]}`)
+	var g Geometry
+	json.Unmarshal([]byte(ring.String()), &g)
+	if err := newQueryBuilder().addGeometryFilter(&g); err == nil || !strings.Contains(err.Error(), "vertices") {
+		t.Errorf("expected the vertex limit, got %v", err)
+	}
+}
 
+func TestCameraNames(t *testing.T) {
+	doc := SearchDoc{StartDate: "2025-01-01T00:00:00", EndDate: "2025-01-02T00:00:00",
+		CameraNames: []string{"Mt Pleasant (Eastbound)", "CAM_01", "Route_10%"}}
+	q, err := BuildSelectQuery(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//plain names can use the lower(camera_name) index, only % makes a pattern
+	if !strings.Contains(q.Text, "(LOWER(camera_name) = ANY($3) OR camera_name ILIKE ANY($4))") {
+		t.Errorf("expected plain names and patterns apart: %s", q.Text)
+	}
+	if !reflect.DeepEqual(q.Params[2], []string{"mt pleasant (eastbound)", "cam_01"}) {
+		t.Errorf("plain names should be one lowered array param, got %v", q.Params[2])
+	}
+	if !reflect.DeepEqual(q.Params[3], []string{`Route\_10%`}) {
+		t.Errorf("_ should be literal in a pattern, got %v", q.Params[3])
+	}
+
+	doc.CameraNames = []string{"CAM_01"}
+	if q, _ = BuildSelectQuery(doc); strings.Contains(q.Text, "ILIKE") || !strings.Contains(q.Text, "LOWER(camera_name) = ANY($3)") {
+		t.Errorf("exact names shouldn't need ilike: %s", q.Text)
+	}
+
+	doc.CameraNames, doc.ExcludeCameras = nil, []string{"test cam", "%test%"}
+	q, _ = BuildSelectQuery(doc)
+	if !strings.Contains(q.Text, "(camera_name IS NULL OR (LOWER(camera_name) <> ALL($3) AND camera_name NOT ILIKE ALL($4)))") {
+		t.Errorf("exclusions: %s", q.Text)
+	}
 }
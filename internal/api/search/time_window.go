@@ -0,0 +1,100 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" //the image is FROM scratch, there's no zoneinfo to load time zones from
+)
+
+// TimeOfDay is a daily window in the search's time_zone. a window whose end is before its start crosses midnight,
+// 22:00 to 04:00 is 10pm until 4am the next morning.
+type TimeOfDay struct {
+	Start string `json:"start"` //HH:MM or HH:MM:SS
+	End   string `json:"end"`
+}
+
+var weekdayNums = map[string]int32{
+	"mon": 1, "monday": 1,
+	"tue": 2, "tuesday": 2,
+	"wed": 3, "wednesday": 3,
+	"thu": 4, "thursday": 4,
+	"fri": 5, "friday": 5,
+	"sat": 6, "saturday": 6,
+	"sun": 7, "sunday": 7,
+}
+
+func parseClock(s string) (time.Time, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time of day, use HH:MM", s)
+}
+
+// addRecurringFilter adds time_of_day and weekdays. reads are stored as UTC without a zone, so read_time is
+// moved into time_zone first. when the window crosses midnight the weekday is the one the window started on,
+// so friday 22:00-04:00 includes saturday 02:00 and not friday 02:00.
+func (qb *queryBuilder) addRecurringFilter(s SearchDoc) error {
+	if s.TimeOfDay == nil && len(s.Weekdays) == 0 {
+		return nil
+	}
+	if s.TimeZone == "" {
+		return invalidFieldf("time_zone", "time_zone is required with time_of_day or weekdays, reads are stored in UTC")
+	}
+	if _, err := time.LoadLocation(s.TimeZone); err != nil || s.TimeZone == "Local" {
+		return invalidFieldf("time_zone", "time_zone %q is not an IANA time zone like America/New_York", s.TimeZone)
+	}
+
+	var days []int32
+	for _, d := range s.Weekdays {
+		switch name := strings.ToLower(strings.TrimSpace(d)); name {
+		case "weekdays":
+			days = append(days, 1, 2, 3, 4, 5)
+		case "weekend":
+			days = append(days, 6, 7)
+		default:
+			n, ok := weekdayNums[name]
+			if !ok {
+				return invalidFieldf("weekdays", "weekdays: %q is not a day of the week", d)
+			}
+			days = append(days, n)
+		}
+	}
+
+	tzPh := qb.nextPlaceholder()
+	qb.args = append(qb.args, s.TimeZone)
+	local := "((read_time AT TIME ZONE 'UTC') AT TIME ZONE " + tzPh + ")"
+	day := local
+
+	if w := s.TimeOfDay; w != nil {
+		start, err := parseClock(w.Start)
+		if err != nil {
+			return invalidFieldf("time_of_day.start", "time_of_day.start: %w", err)
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			return invalidFieldf("time_of_day.end", "time_of_day.end: %w", err)
+		}
+		if start.Equal(end) {
+			return invalidFieldf("time_of_day", "time_of_day start and end can't be the same")
+		}
+
+		startPh, endPh := qb.nextPlaceholder(), qb.nextPlaceholder()
+		qb.args = append(qb.args, start.Format("15:04:05"), end.Format("15:04:05"))
+		clock := local + "::time"
+		if start.Before(end) {
+			qb.conditions = append(qb.conditions, fmt.Sprintf("%s >= %s::time AND %s < %s::time", clock, startPh, clock, endPh))
+		} else {
+			qb.conditions = append(qb.conditions, fmt.Sprintf("(%s >= %s::time OR %s < %s::time)", clock, startPh, clock, endPh))
+			//the after midnight part belongs to the previous day's window
+			day = fmt.Sprintf("CASE WHEN %s < %s::time THEN %s - interval '1 day' ELSE %s END", clock, endPh, local, local)
+		}
+	}
+
+	if len(days) > 0 {
+		qb.addCondition("EXTRACT(ISODOW FROM "+day+")::int = ANY(%s)", days)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTimeOfDayAcrossMidnight(t *testing.T) {
+	doc := SearchDoc{StartDate: "2025-01-01T00:00:00", EndDate: "2025-03-31T23:59:59", PlateNum: "%",
+		TimeOfDay: &TimeOfDay{Start: "22:00", End: "04:00"}, Weekdays: []string{"weekdays"}, TimeZone: "America/New_York"}
+	q, err := BuildSelectQuery(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := "((read_time AT TIME ZONE 'UTC') AT TIME ZONE $3)"
+	for _, want := range []string{
+		"(" + local + "::time >= $4::time OR " + local + "::time < $5::time)",
+		"EXTRACT(ISODOW FROM CASE WHEN " + local + "::time < $5::time THEN " + local + " - interval '1 day' ELSE " + local + " END)::int = ANY($6)",
+	} {
+		if !strings.Contains(q.Text, want) {
+			t.Errorf("expected %q in\n%s", want, q.Text)
+		}
+	}
+	if q.Params[3] != "22:00:00" || q.Params[4] != "04:00:00" || !reflect.DeepEqual(q.Params[5], []int32{1, 2, 3, 4, 5}) {
+		t.Errorf("unexpected params %v", q.Params)
+	}
+	if n := maxPlaceholder(q.Text); n != len(q.Params) {
+		t.Errorf("highest placeholder $%d but %d params", n, len(q.Params))
+	}
+}
+
+func TestTimeOfDaySameDay(t *testing.T) {
+	doc := SearchDoc{StartDate: "2025-01-01T00:00:00", EndDate: "2025-03-31T23:59:59",
+		TimeOfDay: &TimeOfDay{Start: "07:30", End: "09:00"}, Weekdays: []string{"Sat", "sunday"}, TimeZone: "UTC"}
+	q, err := BuildSelectQuery(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(q.Text, "::time >= $4::time AND ") || !strings.Contains(q.Text, "EXTRACT(ISODOW FROM ((read_time") {
+		t.Errorf("expected a same day window and a plain weekday:\n%s", q.Text)
+	}
+	if !reflect.DeepEqual(q.Params[5], []int32{6, 7}) {
+		t.Errorf("weekend days: %v", q.Params[5])
+	}
+}
+
+func TestRecurringFilterRefused(t *testing.T) {
+	for name, doc := range map[string]SearchDoc{
+		"no zone":      {Weekdays: []string{"mon"}},
+		"bad zone":     {Weekdays: []string{"mon"}, TimeZone: "Eastern"},
+		"bad day":      {Weekdays: []string{"funday"}, TimeZone: "UTC"},
+		"bad time":     {TimeOfDay: &TimeOfDay{Start: "10pm", End: "04:00"}, TimeZone: "UTC"},
+		"empty window": {TimeOfDay: &TimeOfDay{Start: "04:00", End: "04:00"}, TimeZone: "UTC"},
+	} {
+		doc.StartDate, doc.EndDate = "2025-01-01T00:00:00", "2025-01-02T00:00:00"
+		if _, err := BuildSelectQuery(doc); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
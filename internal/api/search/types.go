@@ -20,10 +20,18 @@ import (
 	    ]]
 	  }
 	}
+
+	MultiPolygon is the same with an array of polygons. the other shapes:
+	  {"type": "Point", "coordinates": [-74.4, 40.6], "radius": 500}   // within 500 meters
+	  {"type": "BBox", "coordinates": [-74.5, 40.5, -74.3, 40.7]}      // west, south, east, north
+	  {"type": "LineString", "coordinates": [[-74.5, 40.8], [-74.3, 40.85]], "buffer": 100}  // 100 meters either side
 */
+// Coordinates is kept raw since its shape depends on Type, see geometry.go
 type Geometry struct {
-	Type        string        `json:"type"`
-	Coordinates [][][]float64 `json:"coordinates"`
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Radius      float64         `json:"radius,omitempty"` //meters, for a Point
+	Buffer      float64         `json:"buffer,omitempty"` //meters either side, for a LineString
 }
 
 type SearchDoc struct {
@@ -31,11 +39,26 @@ type SearchDoc struct {
 	EndDate     string    `json:"end_date"`
 	Geometry    *Geometry `json:"geometry"`
 	CameraNames []string  `json:"camera_names"`
-	PlateCode   string    `json:"plate_code"`
-	Make        string    `json:"make"`
-	VehicleType string    `json:"vehicle_type"`
-	Color       string    `json:"color"`
-	PlateNum    string    `json:"plate_num"`
+	//a single value or a list, a read matching any of the list matches
+	PlateCode   StringList `json:"plate_code"`
+	Make        StringList `json:"make"`
+	VehicleType StringList `json:"vehicle_type"`
+	Color       StringList `json:"color"`
+	PlateNum    string     `json:"plate_num"`
+	//reads to leave out. plates take % and _ wildcards like plate_num, cameras only % like camera_names (_ is literal)
+	ExcludePlates  []string    `json:"exclude_plates"`
+	ExcludeCameras []string    `json:"exclude_cameras"`
+	Not            *Exclusions `json:"not"`
+	//recurring windows inside start_date..end_date, in time_zone (IANA, required with either)
+	TimeOfDay *TimeOfDay `json:"time_of_day"`
+	Weekdays  []string   `json:"weekdays"` //mon..sun, or weekdays / weekend
+	TimeZone  string     `json:"time_zone"`
+	//how plate_num is matched: exact, wildcard, similar or ocr_confusable. guessed from plate_num when empty
+	PlateMatch string `json:"plate_match"`
+	//minimum trigram similarity (0-1) for plate_match similar, DefaultSimilarity when 0
+	Similarity float64 `json:"similarity"`
+	//read_time (default) or similarity, newest first either way for ties
+	SortBy string `json:"sort_by"`
 	//for limit/offset paging
 	Page     int `json:"page"`
 	PageSize int `json:"page_size"`
@@ -48,7 +71,11 @@ type SearchDoc struct {
 /* Example of what SearchResults json looks like
 {
   "metadata": {
-    "page_count": 50    // Total pages on first request, -1 for subsequent pages
+    "total_count": 1000,  // Total matches on first request, -1 for subsequent pages
+    "page_count": 50,     // Total pages on first request, -1 for subsequent pages
+    "page": 1,
+    "page_size": 20,
+    "is_estimate": false  // true when total_count is the planner's estimate
   },
   "results": [
     {
@@ -76,8 +103,14 @@ type SearchResults struct {
 	AlprRecords []AlprRecord `json:"results"`
 }
 
+// Metadata is the paging for a search. total_count and page_count are -1 when they aren't known,
+// is_estimate is set when total_count is the planner's estimate rather than a count.
 type Metadata struct {
-	PageCount int64 `json:"page_count"`
+	TotalCount int64 `json:"total_count"`
+	PageCount  int64 `json:"page_count"`
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	IsEstimate bool  `json:"is_estimate"`
 }
 
 // NOTE: using pointers so that any db null values will be set to null as the json value. The default serialization for SqlNullString is trash.
@@ -93,6 +126,7 @@ type AlprRecord struct {
 	VehicleType *string         `db:"vehicle_type"  json:"vehicle_type"`
 	Color       *string         `db:"color"         json:"color"`
 	SourceID    *string         `db:"source_id"     json:"source_id"`
+	Score       *float64        `db:"score"         json:"score,omitempty"` //plate similarity, only for plate_match similar and ocr_confusable
 	PlateImg    string          `json:"plate_img"`
 	FullImg     string          `json:"full_img"`
 	SiteID      string          `json:"site_id"`
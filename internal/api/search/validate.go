@@ -0,0 +1,146 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSearch is a SearchDoc that failed validation, see ValidationError.
+var ErrInvalidSearch = errors.New("invalid search document")
+
+// field error codes
+const (
+	CodeRequired      = "required"       //missing or blank
+	CodeInvalidFormat = "invalid_format" //a date or plate code that can't be read
+	CodeInvalidValue  = "invalid_value"  //readable but not an allowed value
+	CodeInvalidRange  = "invalid_range"  //start_date isn't before end_date
+)
+
+// PageSizes are the page sizes a search can ask for.
+var PageSizes = []int{20, 50, 100, 200, 500, 1000}
+
+var plateCodeRe = regexp.MustCompile(`^[A-Za-z]{2}$`)
+
+type FieldError struct {
+	Code    string `json:"code"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every problem found in a SearchDoc. errors.Is(err, ErrInvalidSearch) is true.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Message
+	}
+	return "invalid search document: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidSearch
+}
+
+// invalidField is an error from the query builder about one field, Validate reports it against that field.
+type invalidField struct {
+	field string
+	err   error
+}
+
+func invalidFieldf(field, format string, args ...any) error {
+	return &invalidField{field: field, err: fmt.Errorf(format, args...)}
+}
+
+func (e *invalidField) Error() string { return e.err.Error() }
+func (e *invalidField) Unwrap() error { return e.err }
+
+// Validate checks the whole doc and reports every problem at once, nil or a *ValidationError.
+// paged is false for exports and search jobs, which ignore page and page_size.
+func (s SearchDoc) Validate(paged bool) error {
+	var errs []FieldError
+	fail := func(code, field, format string, args ...any) {
+		errs = append(errs, FieldError{Code: code, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	start, startErr := parseDate(s.StartDate, "start_date", fail)
+	end, endErr := parseDate(s.EndDate, "end_date", fail)
+	if startErr == nil && endErr == nil && !start.Before(end) {
+		fail(CodeInvalidRange, "end_date", "end_date must be after start_date")
+	}
+
+	if paged {
+		switch {
+		case s.Page == 0:
+			fail(CodeRequired, "page", "page is required, the first page is 1")
+		case s.Page < 0:
+			fail(CodeInvalidValue, "page", "page must be 1 or more")
+		}
+		switch {
+		case s.PageSize == 0:
+			fail(CodeRequired, "page_size", "page_size is required")
+		case !slices.Contains(PageSizes, s.PageSize):
+			fail(CodeInvalidValue, "page_size", "page_size must be one of %s", joinInts(PageSizes))
+		}
+	}
+
+	codes := map[string]StringList{"plate_code": s.PlateCode}
+	if s.Not != nil {
+		codes["not.plate_code"] = s.Not.PlateCode
+	}
+	for _, field := range []string{"plate_code", "not.plate_code"} {
+		for i, c := range codes[field] {
+			if !plateCodeRe.MatchString(c) {
+				fail(CodeInvalidFormat, fmt.Sprintf("%s[%d]", field, i), "%s %q must be a 2 letter state code like NJ", field, c)
+			}
+		}
+	}
+
+	//the rest is checked where it's turned into sql
+	qb := newQueryBuilder()
+	var checks []error
+	if s.Geometry != nil {
+		checks = append(checks, qb.addGeometryFilter(s.Geometry))
+	}
+	checks = append(checks, qb.addRecurringFilter(s))
+	_, err := s.plateMatch()
+	checks = append(checks, err)
+	for _, err := range checks {
+		var field *invalidField
+		if errors.As(err, &field) {
+			fail(CodeInvalidValue, field.field, "%s", err.Error())
+		} else if err != nil {
+			fail(CodeInvalidValue, "", "%s", err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func parseDate(value, field string, fail func(code, field, format string, args ...any)) (t time.Time, err error) {
+	if strings.TrimSpace(value) == "" {
+		fail(CodeRequired, field, "%s is required", field)
+		return t, errors.New("missing")
+	}
+	if t, err = parseDateTime(value); err != nil {
+		fail(CodeInvalidFormat, field, "%s %q must be ISO 8601, like 2024-02-01T00:00:00 or 2024-02-01T00:00:00Z", field, value)
+	}
+	return t, err
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = fmt.Sprint(v)
+	}
+	return strings.Join(s, ", ")
+}
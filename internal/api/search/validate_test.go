@@ -0,0 +1,83 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := SearchDoc{StartDate: "2025-01-01T00:00:00", EndDate: "2025-01-02T00:00:00Z", PlateNum: "ABC%", Page: 1, PageSize: 50, PlateCode: StringList{"nj", "NY"}}
+	if err := valid.Validate(true); err != nil {
+		t.Fatalf("valid doc refused: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		doc   string
+		paged bool
+		want  map[string]string //field -> code
+	}{
+		{"empty", `{}`, true, map[string]string{
+			"start_date": CodeRequired, "end_date": CodeRequired, "page": CodeRequired, "page_size": CodeRequired,
+		}},
+		{"camera only", `{"start_date": "2024-02-01T00:00:00", "end_date": "2024-02-02T00:00:00", "camera_names": ["CAM_01"]}`, false, map[string]string{}},
+		{"bad dates", `{"start_date": "02/01/2024", "end_date": "2024-02-01", "plate_num": "%", "page": 1, "page_size": 20}`, true, map[string]string{
+			"start_date": CodeInvalidFormat, "end_date": CodeInvalidFormat,
+		}},
+		{"dates backwards", `{"start_date": "2024-02-02T00:00:00", "end_date": "2024-02-01T00:00:00", "plate_num": "%", "page": 1, "page_size": 20}`, true, map[string]string{
+			"end_date": CodeInvalidRange,
+		}},
+		{"page size not offered", `{"start_date": "2024-02-01T00:00:00", "end_date": "2024-02-02T00:00:00", "plate_num": "%", "page": -1, "page_size": 25}`, true, map[string]string{
+			"page": CodeInvalidValue, "page_size": CodeInvalidValue,
+		}},
+		{"export ignores paging", `{"start_date": "2024-02-01T00:00:00", "end_date": "2024-02-02T00:00:00", "plate_num": "%", "page_size": 25}`, false, map[string]string{}},
+		{"plate codes", `{"start_date": "2024-02-01T00:00:00", "end_date": "2024-02-02T00:00:00", "plate_num": "%", "plate_code": ["NJ", "US-NY"], "not": {"plate_code": "N"}}`, false, map[string]string{
+			"plate_code[1]": CodeInvalidFormat, "not.plate_code[0]": CodeInvalidFormat,
+		}},
+		{"builder checks", `{"start_date": "2024-02-01T00:00:00", "end_date": "2024-02-02T00:00:00", "plate_num": "ABC", "plate_match": "fuzzy",
+			"geometry": {"type": "Point", "coordinates": [-74.4, 40.6]}, "weekdays": ["mon"]}`, false, map[string]string{
+			"plate_match": CodeInvalidValue, "geometry.radius": CodeInvalidValue, "time_zone": CodeInvalidValue,
+		}},
+		{"wrapped builder errors", `{"start_date": "2024-02-01T00:00:00", "end_date": "2024-02-02T00:00:00",
+			"geometry": {"type": "Polygon", "coordinates": "nope"}, "time_zone": "UTC", "time_of_day": {"start": "25:00", "end": "04:00"}}`, false, map[string]string{
+			"geometry.coordinates": CodeInvalidValue, "time_of_day.start": CodeInvalidValue,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc SearchDoc
+			if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			err := doc.Validate(tt.paged)
+			got := map[string]string{}
+			var verr *ValidationError
+			if errors.As(err, &verr) {
+				for _, fe := range verr.Errors {
+					got[fe.Field] = fe.Code
+				}
+			} else if err != nil {
+				t.Fatalf("err = %v, want a *ValidationError", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("fields = %v, want %v", got, tt.want)
+			}
+			for field, code := range tt.want {
+				if got[field] != code {
+					t.Errorf("%s = %q, want %q (%v)", field, got[field], code, err)
+				}
+			}
+			if len(tt.want) > 0 && !errors.Is(err, ErrInvalidSearch) {
+				t.Errorf("errors.Is(err, ErrInvalidSearch) is false")
+			}
+		})
+	}
+}
+
+func TestBadDatesRefused(t *testing.T) {
+	doc := SearchDoc{StartDate: "yesterday", EndDate: "2025-01-02T00:00:00", PlateNum: "%"}
+	if _, err := BuildCountQuery(doc); err == nil {
+		t.Error("a bad date used to drop the date filter, it should be an error")
+	}
+}
@@ -0,0 +1,215 @@
+package searchjobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Eyemetric/alpr_service/internal/api/search"
+	"github.com/Eyemetric/alpr_service/internal/db"
+	"github.com/Eyemetric/alpr_service/internal/repository"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	DefaultPoll = 2 * time.Second
+	// a running job beats every Heartbeat, one that hasn't for StaleAfter is claimed by another worker
+	Heartbeat  = 10 * time.Second
+	StaleAfter = time.Minute
+
+	CleanupInterval = 10 * time.Minute
+
+	// a job claimed more than MaxAttempts times fails instead of running again. one that keeps taking its
+	// worker down (out of memory, a crash) would otherwise be picked up forever.
+	MaxAttempts = 3
+)
+
+// errLost is a job cancelled or claimed by someone else while it ran.
+var errLost = errors.New("search job cancelled or taken over")
+
+// Runner is this replica's bounded pool of search job workers.
+type Runner struct {
+	DB       search.TxBeginner //for the cursor, a *pgxpool.Pool
+	Repo     repository.ALPRRepository
+	WorkerID string
+	Workers  int
+	MaxRows  int64
+	Poll     time.Duration
+}
+
+// Run starts the workers and blocks until ctx is done and they've stopped. a job that's interrupted is left
+// running and another replica picks it up once it goes stale.
+func (r *Runner) Run(ctx context.Context) {
+	if r.Poll <= 0 {
+		r.Poll = DefaultPoll
+	}
+	var wg sync.WaitGroup
+	for i := range r.Workers {
+		workerID := fmt.Sprintf("%s/%d", r.WorkerID, i+1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx, workerID)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Runner) work(ctx context.Context, workerID string) {
+	for ctx.Err() == nil {
+		claimed, err := r.Repo.ClaimSearchJob(ctx, workerID, time.Now().Add(-StaleAfter))
+		if err != nil {
+			log.Printf("search jobs: %v\n", err)
+		}
+		if claimed == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(r.Poll):
+			}
+			continue
+		}
+
+		log.Printf("search job %d started by %s\n", claimed.ID, workerID)
+		rows, err := r.run(ctx, workerID, claimed)
+		switch {
+		case ctx.Err() != nil:
+			log.Printf("search job %d interrupted after %d reads, it'll be picked up again\n", claimed.ID, rows)
+			return
+		case errors.Is(err, errLost):
+			log.Printf("search job %d stopped after %d reads: %v\n", claimed.ID, rows, err)
+			continue
+		}
+
+		finish := db.FinishSearchJobParams{ID: claimed.ID, WorkerID: workerID, Status: StatusDone, Rows: rows}
+		if err != nil {
+			finish.Status, finish.Error = StatusFailed, pgtype.Text{String: err.Error(), Valid: true}
+		}
+		if err := r.Repo.FinishSearchJob(ctx, finish); err != nil {
+			log.Printf("search jobs: %v\n", err)
+		}
+		log.Printf("search job %d %s with %d reads\n", claimed.ID, finish.Status, rows)
+	}
+}
+
+// run copies every read the job's search matches into its results, returning how many.
+func (r *Runner) run(ctx context.Context, workerID string, claimed *db.ClaimSearchJobRow) (int64, error) {
+	if claimed.Attempts > MaxAttempts {
+		return 0, fmt.Errorf("gave up after %d attempts that didn't finish, narrow the search", MaxAttempts)
+	}
+	var doc search.SearchDoc
+	if err := json.Unmarshal(claimed.Doc, &doc); err != nil {
+		return 0, fmt.Errorf("bad search document: %w", err)
+	}
+	query, err := search.BuildExportQuery(doc)
+	if err != nil {
+		return 0, err
+	}
+	//an earlier attempt that died part way may have left some
+	if err := r.Repo.ClearSearchJobResults(ctx, claimed.ID); err != nil {
+		return 0, err
+	}
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go r.heartbeat(jobCtx, cancel, workerID, claimed.ID)
+
+	cursor, err := search.OpenCursor(jobCtx, r.DB, query, search.DefaultExportBatch)
+	if err != nil {
+		return 0, cause(jobCtx, err)
+	}
+	defer cursor.Close(context.WithoutCancel(ctx))
+
+	var rows int64
+	for {
+		records, err := cursor.Next(jobCtx)
+		if err != nil {
+			return rows, cause(jobCtx, err)
+		}
+		if len(records) == 0 {
+			return rows, nil
+		}
+		if rows+int64(len(records)) > r.MaxRows {
+			return rows, fmt.Errorf("the search matches more than %d reads, narrow it down", r.MaxRows)
+		}
+
+		batch := make([][]byte, len(records))
+		for i, rec := range records {
+			if batch[i], err = json.Marshal(rec); err != nil {
+				return rows, err
+			}
+		}
+		add := db.AddSearchJobResultsParams{JobID: claimed.ID, WorkerID: workerID, FirstSeq: rows + 1, Records: batch}
+		stored, err := r.Repo.AddSearchJobResults(jobCtx, add)
+		if err != nil {
+			return rows, cause(jobCtx, err)
+		}
+		if !stored {
+			return rows, errLost
+		}
+		rows += int64(len(records))
+
+		update := db.UpdateSearchJobProgressParams{
+			ID: claimed.ID, WorkerID: workerID, Rows: rows,
+			Progress: progress(doc, records[len(records)-1].ReadTime),
+		}
+		if err := r.Repo.UpdateSearchJobProgress(jobCtx, update); err != nil {
+			return rows, cause(jobCtx, err)
+		}
+	}
+}
+
+// heartbeat keeps the job claimed while it runs and cancels it once it's been cancelled or taken over.
+func (r *Runner) heartbeat(ctx context.Context, cancel context.CancelCauseFunc, workerID string, id int64) {
+	ticker := time.NewTicker(Heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			status, err := r.Repo.SearchJobHeartbeat(ctx, db.SearchJobHeartbeatParams{ID: id, WorkerID: workerID})
+			if err != nil {
+				log.Printf("search jobs: %v\n", err)
+				continue
+			}
+			if status != StatusRunning {
+				cancel(errLost)
+				return
+			}
+		}
+	}
+}
+
+// cause prefers errLost over the context error a cancelled query comes back with.
+func cause(ctx context.Context, err error) error {
+	if c := context.Cause(ctx); errors.Is(c, errLost) {
+		return c
+	}
+	return err
+}
+
+// RunCleanup deletes jobs that finished more than retention ago, with their results, every CleanupInterval.
+// a singleton job, run by the leader.
+func RunCleanup(ctx context.Context, repo repository.ALPRRepository, retention time.Duration) {
+	ticker := time.NewTicker(CleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := repo.DeleteExpiredSearchJobs(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("search job cleanup: %v\n", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("search job cleanup: %d expired jobs deleted\n", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package searchjobs
+
+/* Search jobs run searches too broad to answer inside a request, like A% over a year.
+A job is a SearchDoc saved as queued. Worker replicas claim jobs (Runner), walk the search with the
+export cursor and copy every matching read into search_job_results in result order, reporting progress
+as they go. Once done the results are paged or exported from that table, and deleted with the job
+after the configured retention. A worker that dies mid job stops heartbeating and another one claims
+the job again from the start.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Eyemetric/alpr_service/internal/api/search"
+	"github.com/Eyemetric/alpr_service/internal/db"
+	"github.com/Eyemetric/alpr_service/internal/repository"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusDone      = "done"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+var (
+	ErrInvalidJob = errors.New("invalid search job")
+	ErrNotFound   = errors.New("search job not found")
+	ErrNotDone    = errors.New("search job isn't done")
+	ErrFinished   = errors.New("search job already finished")
+)
+
+// Job is a job's status as the api returns it.
+type Job struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+	Rows   int64  `json:"rows"` //reads found so far
+	//0-1, worked out from how far into the date range the search is. null when sorted by similarity
+	Progress   *float64        `json:"progress"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"` //when the job and its results are deleted
+	Search     json.RawMessage `json:"search"`
+}
+
+func newJob(row *db.SearchJob, retention time.Duration) *Job {
+	job := &Job{
+		ID:        row.ID,
+		Status:    row.Status,
+		Rows:      row.Rows,
+		Error:     row.Error.String,
+		CreatedAt: row.CreatedAt.Time,
+		Search:    row.Doc,
+	}
+	if row.Progress.Valid {
+		job.Progress = &row.Progress.Float64
+	}
+	if row.StartedAt.Valid {
+		job.StartedAt = &row.StartedAt.Time
+	}
+	if row.FinishedAt.Valid {
+		job.FinishedAt = &row.FinishedAt.Time
+		if retention > 0 {
+			expires := row.FinishedAt.Time.Add(retention)
+			job.ExpiresAt = &expires
+		}
+	}
+	return job
+}
+
+// Submit checks the search and queues it. an invalid doc is a *search.ValidationError.
+func Submit(ctx context.Context, doc search.SearchDoc, retention time.Duration, repo repository.ALPRRepository) (*Job, error) {
+	if err := doc.Validate(false); err != nil {
+		return nil, err
+	}
+	if _, err := search.BuildExportQuery(doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidJob, err)
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	id, err := repo.CreateSearchJob(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	return Get(ctx, id, retention, repo)
+}
+
+func Get(ctx context.Context, id int64, retention time.Duration, repo repository.ALPRRepository) (*Job, error) {
+	row, err := repo.GetSearchJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, ErrNotFound
+	}
+	return newJob(row, retention), nil
+}
+
+// Cancel stops a queued or running job. a running job's worker notices on its next heartbeat.
+// whatever it found so far is kept until the job expires, but can't be fetched.
+func Cancel(ctx context.Context, id int64, retention time.Duration, repo repository.ALPRRepository) (*Job, error) {
+	cancelled, err := repo.CancelSearchJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	job, err := Get(ctx, id, retention, repo)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return job, fmt.Errorf("%w: it's %s", ErrFinished, job.Status)
+	}
+	return job, nil
+}
+
+// Results returns a page of a done job's reads. pages are 1 based.
+func Results(ctx context.Context, id int64, page, pageSize int, repo repository.ALPRRepository) ([]search.AlprRecord, *Job, error) {
+	job, err := done(ctx, id, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	records, err := list(ctx, id, int64(page-1)*int64(pageSize), pageSize, repo)
+	return records, job, err
+}
+
+// Each calls fn with every read of a done job in order, a batch at a time.
+func Each(ctx context.Context, id int64, repo repository.ALPRRepository, fn func(search.AlprRecord) error) error {
+	if _, err := done(ctx, id, repo); err != nil {
+		return err
+	}
+	var seq int64
+	for {
+		records, err := list(ctx, id, seq, search.DefaultExportBatch, repo)
+		if err != nil {
+			return err
+		}
+		for _, rec := range records {
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+		if len(records) < search.DefaultExportBatch {
+			return nil
+		}
+		seq += int64(len(records))
+	}
+}
+
+func done(ctx context.Context, id int64, repo repository.ALPRRepository) (*Job, error) {
+	job, err := Get(ctx, id, 0, repo)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusDone {
+		return job, fmt.Errorf("%w: it's %s", ErrNotDone, job.Status)
+	}
+	return job, nil
+}
+
+func list(ctx context.Context, id, afterSeq int64, n int, repo repository.ALPRRepository) ([]search.AlprRecord, error) {
+	rows, err := repo.ListSearchJobResults(ctx, db.ListSearchJobResultsParams{JobID: id, AfterSeq: afterSeq, MaxRows: int32(n)})
+	if err != nil {
+		return nil, err
+	}
+	records := make([]search.AlprRecord, len(rows))
+	for i, row := range rows {
+		if err := json.Unmarshal(row, &records[i]); err != nil {
+			return nil, fmt.Errorf("bad stored result for search job %d: %w", id, err)
+		}
+	}
+	return records, nil
+}
+
+// progress is how far a read_time ordered search has got through its date range, newest first.
+// invalid for similarity ordered searches, the read times come in any order.
+func progress(doc search.SearchDoc, last time.Time) pgtype.Float8 {
+	if doc.SortBy == search.SortSimilarity {
+		return pgtype.Float8{}
+	}
+	start, end, err := doc.DateRange()
+	if err != nil || !start.Before(end) {
+		return pgtype.Float8{}
+	}
+	p := float64(end.Sub(last)) / float64(end.Sub(start))
+	return pgtype.Float8{Float64: min(max(p, 0), 1), Valid: true}
+}
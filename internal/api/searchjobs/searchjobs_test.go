@@ -0,0 +1,158 @@
+package searchjobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Eyemetric/alpr_service/internal/api/search"
+	"github.com/Eyemetric/alpr_service/internal/db"
+	"github.com/Eyemetric/alpr_service/internal/repository"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeRepo keeps jobs and results in memory, only the search job methods are implemented
+type fakeRepo struct {
+	repository.ALPRRepository
+	jobs    map[int64]*db.SearchJob
+	results map[int64][][]byte
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{jobs: map[int64]*db.SearchJob{}, results: map[int64][][]byte{}}
+}
+
+func (f *fakeRepo) CreateSearchJob(ctx context.Context, doc []byte) (int64, error) {
+	id := int64(len(f.jobs) + 1)
+	f.jobs[id] = &db.SearchJob{ID: id, Status: StatusQueued, Doc: doc, CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+	return id, nil
+}
+
+func (f *fakeRepo) GetSearchJob(ctx context.Context, id int64) (*db.SearchJob, error) {
+	return f.jobs[id], nil
+}
+
+func (f *fakeRepo) CancelSearchJob(ctx context.Context, id int64) (bool, error) {
+	job := f.jobs[id]
+	if job == nil || (job.Status != StatusQueued && job.Status != StatusRunning) {
+		return false, nil
+	}
+	job.Status = StatusCancelled
+	return true, nil
+}
+
+func (f *fakeRepo) ListSearchJobResults(ctx context.Context, p db.ListSearchJobResultsParams) ([][]byte, error) {
+	all := f.results[p.JobID]
+	from := min(int(p.AfterSeq), len(all))
+	to := min(from+int(p.MaxRows), len(all))
+	return all[from:to], nil
+}
+
+func (f *fakeRepo) finished(t *testing.T, reads int) int64 {
+	t.Helper()
+	id, _ := f.CreateSearchJob(context.Background(), []byte(`{}`))
+	f.jobs[id].Status = StatusDone
+	f.jobs[id].Rows = int64(reads)
+	f.jobs[id].FinishedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	for i := range reads {
+		plate := fmt.Sprintf("P%d", i+1)
+		rec, _ := json.Marshal(search.AlprRecord{PlateNum: &plate})
+		f.results[id] = append(f.results[id], rec)
+	}
+	return id
+}
+
+func TestSubmit(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo()
+
+	_, err := Submit(ctx, search.SearchDoc{PlateNum: "A%"}, time.Hour, repo)
+	if !errors.Is(err, search.ErrInvalidSearch) {
+		t.Fatalf("err = %v, want ErrInvalidSearch", err)
+	}
+
+	doc := search.SearchDoc{StartDate: "2024-01-01T00:00:00", EndDate: "2025-01-01T00:00:00", PlateNum: "A%"}
+	job, err := Submit(ctx, doc, time.Hour, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != StatusQueued || job.ExpiresAt != nil {
+		t.Errorf("job = %+v", job)
+	}
+	if _, _, err := Results(ctx, job.ID, 1, 20, repo); !errors.Is(err, ErrNotDone) {
+		t.Errorf("results of a queued job: %v, want ErrNotDone", err)
+	}
+	if _, err := Cancel(ctx, job.ID, time.Hour, repo); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Cancel(ctx, job.ID, time.Hour, repo); !errors.Is(err, ErrFinished) {
+		t.Errorf("second cancel: %v, want ErrFinished", err)
+	}
+	if _, err := Get(ctx, 99, time.Hour, repo); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing job: %v, want ErrNotFound", err)
+	}
+}
+
+func TestResults(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo()
+	id := repo.finished(t, 2500)
+
+	records, job, err := Results(ctx, id, 3, 1000, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 500 || *records[0].PlateNum != "P2001" || job.Rows != 2500 {
+		t.Errorf("page 3 = %d records starting %s", len(records), *records[0].PlateNum)
+	}
+	if job.ExpiresAt != nil {
+		t.Error("expires_at isn't known without the retention")
+	}
+
+	n := 0
+	err = Each(ctx, id, repo, func(rec search.AlprRecord) error {
+		n++
+		if want := fmt.Sprintf("P%d", n); *rec.PlateNum != want {
+			return fmt.Errorf("read %d is %s, want %s", n, *rec.PlateNum, want)
+		}
+		return nil
+	})
+	if err != nil || n != 2500 {
+		t.Errorf("each: %d reads, %v", n, err)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	doc := search.SearchDoc{StartDate: "2025-01-01T00:00:00", EndDate: "2025-01-11T00:00:00"}
+	tests := []struct {
+		last time.Time
+		want float64
+	}{
+		{time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC), 0},
+		{time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC), 0.25},
+		{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 1},
+		{time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), 1},
+	}
+	for _, tt := range tests {
+		if p := progress(doc, tt.last); !p.Valid || p.Float64 != tt.want {
+			t.Errorf("progress at %s = %+v, want %g", tt.last, p, tt.want)
+		}
+	}
+
+	doc.SortBy = search.SortSimilarity
+	if p := progress(doc, time.Now()); p.Valid {
+		t.Error("similarity ordered searches have no progress")
+	}
+}
+
+func TestRunGivesUp(t *testing.T) {
+	r := &Runner{Repo: newFakeRepo(), MaxRows: 100}
+	claimed := &db.ClaimSearchJobRow{ID: 1, Doc: []byte(`{}`), Attempts: MaxAttempts + 1}
+	//the fake has no ClearSearchJobResults and there's no DB, so getting past the check would panic
+	if _, err := r.run(context.Background(), "w1", claimed); err == nil {
+		t.Errorf("a job claimed %d times should fail", claimed.Attempts)
+	}
+}
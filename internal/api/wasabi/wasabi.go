@@ -4,7 +4,7 @@ package wasabi
 The purpose of Wasabi.go is to presign urls.
 Presigning Urls allows us to provide safe image links without
 requiring the user to authenticate first. It's baked into the presign.
-These links expire after 6hours.
+These links expire after the configured presign ttl (6hrs by default).
 Presigning also allows us to give clients a url they can use to get images directly from the S3 store
 rather than call a middleware api which will incur ingress and egress costs on Azure.
 */
@@ -21,15 +21,16 @@ import (
 )
 
 const (
-	PRESIGN_EXPIRES = 360 * time.Minute //6hr cache.
+	PRESIGN_EXPIRES = 360 * time.Minute //6hr cache. default when no ttl is given
 )
 
 type Wasabi struct {
 	s3Client      *s3.Client
 	presignClient *s3.PresignClient
+	expires       time.Duration
 }
 
-// creates a secure but publicly accessible image link with a 6hr expiration
+// creates a secure but publicly accessible image link that expires after the presign ttl
 func (w *Wasabi) PresignUrl(bucket, objectKey string) (string, error) {
 	//we need the bucket and the object.
 	//prepare presign request input
@@ -40,7 +41,7 @@ func (w *Wasabi) PresignUrl(bucket, objectKey string) (string, error) {
 	}
 
 	presignResult, err := w.presignClient.PresignGetObject(context.TODO(), getObjInput, func(po *s3.PresignOptions) {
-		po.Expires = PRESIGN_EXPIRES
+		po.Expires = w.expires
 	})
 
 	if err != nil {
@@ -50,8 +51,21 @@ func (w *Wasabi) PresignUrl(bucket, objectKey string) (string, error) {
 
 }
 
+// HeadObject checks that an object exists and that our credentials are allowed to read it.
+// Used by the readiness check, presigning alone never talks to the server so it can't catch bad keys.
+func (w *Wasabi) HeadObject(ctx context.Context, bucket, objectKey string) error {
+	_, err := w.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(objectKey),
+	})
+	return err
+}
+
 // return a struct that wraps the aws S3 client for Wasabi
-func NewWasabi(s3Host, s3Region string) (*Wasabi, error) {
+func NewWasabi(s3Host, s3Region string, presignExpires time.Duration) (*Wasabi, error) {
+	if presignExpires <= 0 {
+		presignExpires = PRESIGN_EXPIRES
+	}
 	s3Endpoint := fmt.Sprintf("https://%s", s3Host)
 	usePathStyle := true
 	//lets the sdk know we aren't calling official aws servers. GOOD LORD! This aws api is HOT TRASH!
@@ -87,6 +101,7 @@ func NewWasabi(s3Host, s3Region string) (*Wasabi, error) {
 	wasabi := &Wasabi{
 		s3Client:      s3Client,
 		presignClient: presignClient,
+		expires:       presignExpires,
 	}
 
 	return wasabi, nil
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadDevDefaults(t *testing.T) {
+	t.Setenv("ALPR_ENV", "dev")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("dev config should load with no other settings: %v", err)
+	}
+	if cfg.DB.URL != devDefaults.dbURL {
+		t.Errorf("expected dev db url, got %s", cfg.DB.URL)
+	}
+}
+
+func TestLoadRefusesDevDefaultsInProd(t *testing.T) {
+	t.Setenv("ALPR_ENV", "prod")
+	t.Setenv("ALPR_DB", devDefaults.dbURL)
+	t.Setenv("PLATEHIT_URL", "https://example.com/api/poi/alpr")
+	t.Setenv("NJSNAP_TOKEN", devDefaults.authToken)
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("expected prod config with dev defaults to be refused")
+	}
+	for _, want := range []string{"db.url is the dev default", "auth_token is the dev default"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got: %v", want, err)
+		}
+	}
+}
+
+// migrate only needs the db, the alert settings the service requires shouldn't stop it
+func TestLoadDB(t *testing.T) {
+	t.Setenv("ALPR_ENV", "prod")
+	t.Setenv("ALPR_DB", "postgresql://alpr:hunter2-pw@db:5432/snap")
+
+	if _, err := Load(); err == nil {
+		t.Fatal("expected the full config to need the alert settings")
+	}
+	cfg, err := LoadDB()
+	if err != nil || cfg.DB.URL != "postgresql://alpr:hunter2-pw@db:5432/snap" {
+		t.Fatalf("db only config should load: %+v %v", cfg.DB, err)
+	}
+
+	t.Setenv("ALPR_DB", devDefaults.dbURL)
+	if _, err := LoadDB(); err == nil || !strings.Contains(err.Error(), "dev default") {
+		t.Errorf("expected the dev db url to be refused in prod, got %v", err)
+	}
+}
+
+func TestFileThenEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `
+env: prod
+db:
+  url: postgresql://alpr:hunter2-pw@db:5432/snap
+  max_conns: 4
+s3:
+  bucket: from-file
+  presign_ttl: 1h
+alert:
+  platehit_url: https://example.com/api/poi/alpr
+  auth_token: file-token
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ALPR_CONFIG", path)
+	t.Setenv("S3_BUCKET", "from-env")
+	t.Setenv("INGEST_API_KEYS", "genetec-key-0000000001=genetec, rekor-key-00000000002=openalpr")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if cfg.S3.Bucket != "from-env" {
+		t.Errorf("env should override file, got bucket %s", cfg.S3.Bucket)
+	}
+	if cfg.DB.MaxConns != 4 || cfg.S3.PresignTTL.Hours() != 1 {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+
+	if cfg.Ingest.APIKeys["rekor-key-00000000002"] != "openalpr" || len(cfg.Ingest.APIKeys) != 2 {
+		t.Errorf("ingest api keys not parsed: %v", cfg.Ingest.APIKeys)
+	}
+
+	out := cfg.String()
+	if strings.Contains(out, "hunter2-pw") || strings.Contains(out, "file-token") || strings.Contains(out, "genetec-key") {
+		t.Errorf("printed config leaks secrets:\n%s", out)
+	}
+}
+
+func TestRoleAndWorkerID(t *testing.T) {
+	t.Setenv("ALPR_ENV", "dev")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Role != RoleAll {
+		t.Errorf("expected role to default to %s, got %s", RoleAll, cfg.Role)
+	}
+	if cfg.WorkerID == "" || cfg.WorkerID == "worker-1" {
+		t.Errorf("expected a per process worker id, got %q", cfg.WorkerID)
+	}
+
+	t.Setenv("ALPR_WORKER_ID", "alpr-2")
+	t.Setenv("ALPR_ROLE", "api")
+	if cfg, err = Load(); err != nil || cfg.WorkerID != "alpr-2" || cfg.Role != RoleAPI {
+		t.Errorf("env not applied: %+v %v", cfg, err)
+	}
+
+	t.Setenv("ALPR_ROLE", "everything")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "role must be") {
+		t.Errorf("expected an unknown role to be refused, got %v", err)
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := map[string]string{
+		"":                                       "",
+		"postgresql://alpr:secret@db:5432/snap":  "postgresql://alpr:%2A%2A%2A%2A%2A%2A%2A%2A@db:5432/snap",
+		"postgres://db/snap?password=secret&x=1": "postgres://db/snap?password=%2A%2A%2A%2A%2A%2A%2A%2A&x=1",
+		"postgres://alpr@db/snap":                "postgres://alpr@db/snap",
+		"host=localhost user=a password=secret dbname=snap": redacted,
+		"db:5432/snap?password=secret":                      redacted,
+	}
+	for raw, want := range tests {
+		if got := redactURL(raw); got != want || strings.Contains(got, "secret") {
+			t.Errorf("redactURL(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
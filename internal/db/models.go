@@ -38,8 +38,14 @@ type Alpr struct {
 	ReadID pgtype.Text `json:"readID"`
 	Make   pgtype.Text `json:"make"`
 	// sedan, suv, etc
-	VehicleType pgtype.Text `json:"vehicleType"`
-	Color       pgtype.Text `json:"color"`
+	VehicleType     pgtype.Text   `json:"vehicleType"`
+	Color           pgtype.Text   `json:"color"`
+	ImageWidth      pgtype.Int4   `json:"imageWidth"`
+	ImageHeight     pgtype.Int4   `json:"imageHeight"`
+	PlateRegion     []byte        `json:"plateRegion"`
+	Occlusion       pgtype.Float4 `json:"occlusion"`
+	SourceType      pgtype.Text   `json:"sourceType"`
+	FieldMapVersion pgtype.Int4   `json:"fieldMapVersion"`
 }
 
 type AlprDeadletter struct {
@@ -55,19 +61,31 @@ type AlprDeadletter struct {
 }
 
 type AlprIngest struct {
-	ID          int64              `json:"id"`
-	Doc         []byte             `json:"doc"`
-	PlateNum    pgtype.Text        `json:"plateNum"`
-	ReadTime    pgtype.Timestamptz `json:"readTime"`
-	CameraName  pgtype.Text        `json:"cameraName"`
-	PlateCode   pgtype.Text        `json:"plateCode"`
-	ImageID     pgtype.Text        `json:"imageID"`
-	Location    interface{}        `json:"location"`
-	ReadID      pgtype.Text        `json:"readID"`
-	Make        pgtype.Text        `json:"make"`
-	VehicleType pgtype.Text        `json:"vehicleType"`
-	Color       pgtype.Text        `json:"color"`
-	InsertedAt  pgtype.Timestamptz `json:"insertedAt"`
+	ID              int64              `json:"id"`
+	Doc             []byte             `json:"doc"`
+	PlateNum        pgtype.Text        `json:"plateNum"`
+	ReadTime        pgtype.Timestamptz `json:"readTime"`
+	CameraName      pgtype.Text        `json:"cameraName"`
+	PlateCode       pgtype.Text        `json:"plateCode"`
+	ImageID         pgtype.Text        `json:"imageID"`
+	Location        interface{}        `json:"location"`
+	ReadID          pgtype.Text        `json:"readID"`
+	Make            pgtype.Text        `json:"make"`
+	VehicleType     pgtype.Text        `json:"vehicleType"`
+	Color           pgtype.Text        `json:"color"`
+	InsertedAt      pgtype.Timestamptz `json:"insertedAt"`
+	ImageWidth      pgtype.Int4        `json:"imageWidth"`
+	ImageHeight     pgtype.Int4        `json:"imageHeight"`
+	PlateRegion     []byte             `json:"plateRegion"`
+	Occlusion       pgtype.Float4      `json:"occlusion"`
+	SourceType      pgtype.Text        `json:"sourceType"`
+	FieldMapVersion pgtype.Int4        `json:"fieldMapVersion"`
+}
+
+type AlprUtilIngestFieldMap struct {
+	Version    int32    `json:"version"`
+	ColumnName string   `json:"columnName"`
+	DocPath    []string `json:"docPath"`
 }
 
 type Hotlist struct {
@@ -84,11 +102,21 @@ type Hotlist struct {
 	UpdatedAt             pgtype.Timestamptz `json:"updatedAt"`
 }
 
+type Camera struct {
+	CameraName string           `json:"cameraName"`
+	SourceID   pgtype.Text      `json:"sourceID"`
+	FirstSeen  pgtype.Timestamp `json:"firstSeen"`
+	LastSeen   pgtype.Timestamp `json:"lastSeen"`
+	ReadCount  int64            `json:"readCount"`
+	Location   interface{}      `json:"location"`
+}
+
 type HotlistAlertEvent struct {
-	ID        int64              `json:"id"`
-	Kind      string             `json:"kind"`
-	CreatedAt pgtype.Timestamptz `json:"createdAt"`
-	Details   []byte             `json:"details"`
+	ID           int64              `json:"id"`
+	Kind         string             `json:"kind"`
+	CreatedAt    pgtype.Timestamptz `json:"createdAt"`
+	Details      []byte             `json:"details"`
+	DispatchedAt pgtype.Timestamptz `json:"dispatchedAt"`
 }
 
 type HotlistAlertState struct {
@@ -99,3 +127,69 @@ type HotlistAlertState struct {
 	VendorDownNotifiedAt pgtype.Timestamptz `json:"vendorDownNotifiedAt"`
 	NextDueAt            pgtype.Timestamptz `json:"nextDueAt"`
 }
+
+type LegalHold struct {
+	ID          int64              `json:"id"`
+	CaseNumber  string             `json:"caseNumber"`
+	Description pgtype.Text        `json:"description"`
+	CreatedBy   pgtype.Text        `json:"createdBy"`
+	CreatedAt   pgtype.Timestamptz `json:"createdAt"`
+	ReleasedAt  pgtype.Timestamptz `json:"releasedAt"`
+	ReleasedBy  pgtype.Text        `json:"releasedBy"`
+}
+
+type LegalHoldCriterium struct {
+	ID        int64            `json:"id"`
+	HoldID    int64            `json:"holdID"`
+	ReadID    pgtype.Text      `json:"readID"`
+	PlateNum  pgtype.Text      `json:"plateNum"`
+	StartTime pgtype.Timestamp `json:"startTime"`
+	EndTime   pgtype.Timestamp `json:"endTime"`
+}
+
+type LegalHoldRead struct {
+	HoldID     int64              `json:"holdID"`
+	AlprID     int64              `json:"alprID"`
+	ReadTime   pgtype.Timestamp   `json:"readTime"`
+	ReadID     pgtype.Text        `json:"readID"`
+	PlateNum   pgtype.Text        `json:"plateNum"`
+	CameraName pgtype.Text        `json:"cameraName"`
+	ImageKeys  []string           `json:"imageKeys"`
+	AlprRow    []byte             `json:"alprRow"`
+	HeldAt     pgtype.Timestamptz `json:"heldAt"`
+}
+
+type RetentionAudit struct {
+	ID           int64              `json:"id"`
+	TableName    string             `json:"tableName"`
+	Method       string             `json:"method"`
+	Cutoff       pgtype.Timestamptz `json:"cutoff"`
+	DryRun       bool               `json:"dryRun"`
+	Chunks       int64              `json:"chunks"`
+	RowsAffected int64              `json:"rowsAffected"`
+	Error        pgtype.Text        `json:"error"`
+	StartedAt    pgtype.Timestamptz `json:"startedAt"`
+	FinishedAt   pgtype.Timestamptz `json:"finishedAt"`
+	Details      []byte             `json:"details"`
+}
+
+type SearchJob struct {
+	ID          int64              `json:"id"`
+	Status      string             `json:"status"`
+	Doc         []byte             `json:"doc"`
+	Rows        int64              `json:"rows"`
+	Progress    pgtype.Float8      `json:"progress"`
+	Error       pgtype.Text        `json:"error"`
+	WorkerID    pgtype.Text        `json:"workerID"`
+	Attempts    int32              `json:"attempts"`
+	CreatedAt   pgtype.Timestamptz `json:"createdAt"`
+	StartedAt   pgtype.Timestamptz `json:"startedAt"`
+	HeartbeatAt pgtype.Timestamptz `json:"heartbeatAt"`
+	FinishedAt  pgtype.Timestamptz `json:"finishedAt"`
+}
+
+type SearchJobResult struct {
+	JobID  int64  `json:"jobID"`
+	Seq    int64  `json:"seq"`
+	Record []byte `json:"record"`
+}
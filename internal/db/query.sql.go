@@ -11,6 +11,103 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const addSearchJobResults = `-- name: AddSearchJobResults :execrows
+with owner as (
+  select id from search_jobs
+  where id = $1::bigint and worker_id = $2::text and status = 'running'
+  for share)
+insert into search_job_results (job_id, seq, record)
+select owner.id, $3::bigint + t.o - 1, t.r
+from owner, unnest($4::jsonb[]) with ordinality as t(r, o)
+`
+
+type AddSearchJobResultsParams struct {
+	JobID    int64    `json:"jobID"`
+	WorkerID string   `json:"workerID"`
+	FirstSeq int64    `json:"firstSeq"`
+	Records  [][]byte `json:"records"`
+}
+
+func (q *Queries) AddSearchJobResults(ctx context.Context, arg AddSearchJobResultsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, addSearchJobResults,
+		arg.JobID,
+		arg.WorkerID,
+		arg.FirstSeq,
+		arg.Records,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const cancelSearchJob = `-- name: CancelSearchJob :execrows
+update search_jobs set status = 'cancelled', finished_at = now()
+where id = $1::bigint and status in ('queued', 'running')
+`
+
+func (q *Queries) CancelSearchJob(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, cancelSearchJob, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const captureLegalHolds = `-- name: CaptureLegalHolds :one
+select alpr_util.legal_hold_capture(null, $1::timestamp)::bigint as captured
+`
+
+func (q *Queries) CaptureLegalHolds(ctx context.Context, before pgtype.Timestamp) (int64, error) {
+	row := q.db.QueryRow(ctx, captureLegalHolds, before)
+	var captured int64
+	err := row.Scan(&captured)
+	return captured, err
+}
+
+const claimAlertEvents = `-- name: ClaimAlertEvents :many
+update hotlist_alert_events e set dispatched_at = now()
+where e.id in (
+  select id from hotlist_alert_events
+  where dispatched_at is null
+  order by id
+  limit $1::integer
+  for update skip locked)
+returning e.id, e.kind, e.created_at, e.details
+`
+
+type ClaimAlertEventsRow struct {
+	ID        int64              `json:"id"`
+	Kind      string             `json:"kind"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	Details   []byte             `json:"details"`
+}
+
+func (q *Queries) ClaimAlertEvents(ctx context.Context, maxRows int32) ([]ClaimAlertEventsRow, error) {
+	rows, err := q.db.Query(ctx, claimAlertEvents, maxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ClaimAlertEventsRow{}
+	for rows.Next() {
+		var i ClaimAlertEventsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.CreatedAt,
+			&i.Details,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const claimDue = `-- name: ClaimDue :many
 SELECT
     id::bigint as id,
@@ -50,6 +147,148 @@ func (q *Queries) ClaimDue(ctx context.Context, arg ClaimDueParams) ([]ClaimDueR
 	return items, nil
 }
 
+const claimSearchJob = `-- name: ClaimSearchJob :one
+update search_jobs j set status = 'running', worker_id = $1::text, attempts = j.attempts + 1,
+  started_at = now(), heartbeat_at = now(), rows = 0, progress = null, error = null
+where j.id = (
+  select id from search_jobs
+  where status = 'queued' or (status = 'running' and heartbeat_at < $2::timestamptz)
+  order by id
+  limit 1
+  for update skip locked)
+returning j.id, j.doc, j.attempts
+`
+
+type ClaimSearchJobParams struct {
+	WorkerID    string             `json:"workerID"`
+	StaleBefore pgtype.Timestamptz `json:"staleBefore"`
+}
+
+type ClaimSearchJobRow struct {
+	ID       int64  `json:"id"`
+	Doc      []byte `json:"doc"`
+	Attempts int32  `json:"attempts"`
+}
+
+func (q *Queries) ClaimSearchJob(ctx context.Context, arg ClaimSearchJobParams) (ClaimSearchJobRow, error) {
+	row := q.db.QueryRow(ctx, claimSearchJob, arg.WorkerID, arg.StaleBefore)
+	var i ClaimSearchJobRow
+	err := row.Scan(&i.ID, &i.Doc, &i.Attempts)
+	return i, err
+}
+
+const clearSearchJobResults = `-- name: ClearSearchJobResults :exec
+delete from search_job_results where job_id = $1::bigint
+`
+
+func (q *Queries) ClearSearchJobResults(ctx context.Context, jobID int64) error {
+	_, err := q.db.Exec(ctx, clearSearchJobResults, jobID)
+	return err
+}
+
+const createLegalHold = `-- name: CreateLegalHold :one
+select alpr_util.legal_hold_create($1::jsonb)::bigint as id
+`
+
+func (q *Queries) CreateLegalHold(ctx context.Context, doc []byte) (int64, error) {
+	row := q.db.QueryRow(ctx, createLegalHold, doc)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const createSearchJob = `-- name: CreateSearchJob :one
+insert into search_jobs (doc) values ($1::jsonb) returning id
+`
+
+func (q *Queries) CreateSearchJob(ctx context.Context, doc []byte) (int64, error) {
+	row := q.db.QueryRow(ctx, createSearchJob, doc)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const deleteDeadletter = `-- name: DeleteDeadletter :exec
+delete from public.alpr_deadletter where id = $1::bigint
+`
+
+func (q *Queries) DeleteDeadletter(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, deleteDeadletter, id)
+	return err
+}
+
+const deleteExpiredSearchJobs = `-- name: DeleteExpiredSearchJobs :execrows
+delete from search_jobs where finished_at < $1::timestamptz
+`
+
+func (q *Queries) DeleteExpiredSearchJobs(ctx context.Context, before pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredSearchJobs, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const finishSearchJob = `-- name: FinishSearchJob :exec
+update search_jobs set status = $1::text, error = $2::text, rows = $3::bigint,
+  progress = case when $1::text = 'done' then 1 else progress end, finished_at = now()
+where id = $4::bigint and worker_id = $5::text and status = 'running'
+`
+
+type FinishSearchJobParams struct {
+	Status   string      `json:"status"`
+	Error    pgtype.Text `json:"error"`
+	Rows     int64       `json:"rows"`
+	ID       int64       `json:"id"`
+	WorkerID string      `json:"workerID"`
+}
+
+func (q *Queries) FinishSearchJob(ctx context.Context, arg FinishSearchJobParams) error {
+	_, err := q.db.Exec(ctx, finishSearchJob,
+		arg.Status,
+		arg.Error,
+		arg.Rows,
+		arg.ID,
+		arg.WorkerID,
+	)
+	return err
+}
+
+const getAlertState = `-- name: GetAlertState :one
+select mode::text as mode, phase_attempts, first_failed_at, next_due_at
+from hotlist_alert_state where id = 1
+`
+
+type GetAlertStateRow struct {
+	Mode          string             `json:"mode"`
+	PhaseAttempts int32              `json:"phaseAttempts"`
+	FirstFailedAt pgtype.Timestamptz `json:"firstFailedAt"`
+	NextDueAt     pgtype.Timestamptz `json:"nextDueAt"`
+}
+
+func (q *Queries) GetAlertState(ctx context.Context) (GetAlertStateRow, error) {
+	row := q.db.QueryRow(ctx, getAlertState)
+	var i GetAlertStateRow
+	err := row.Scan(
+		&i.Mode,
+		&i.PhaseAttempts,
+		&i.FirstFailedAt,
+		&i.NextDueAt,
+	)
+	return i, err
+}
+
+const getLegalHold = `-- name: GetLegalHold :one
+select alpr_util.legal_hold_json($1::bigint) as hold
+`
+
+func (q *Queries) GetLegalHold(ctx context.Context, id int64) ([]byte, error) {
+	row := q.db.QueryRow(ctx, getLegalHold, id)
+	var hold []byte
+	err := row.Scan(&hold)
+	return hold, err
+}
+
 const getPlateHit = `-- name: GetPlateHit :many
 SELECT
     h.hotlist_id AS ID,
@@ -163,6 +402,31 @@ func (q *Queries) GetPlateHit(ctx context.Context, arg GetPlateHitParams) ([]Get
 	return items, nil
 }
 
+const getSearchJob = `-- name: GetSearchJob :one
+select id, status, doc, rows, progress, error, worker_id, attempts, created_at, started_at, heartbeat_at, finished_at
+from search_jobs where id = $1::bigint
+`
+
+func (q *Queries) GetSearchJob(ctx context.Context, id int64) (SearchJob, error) {
+	row := q.db.QueryRow(ctx, getSearchJob, id)
+	var i SearchJob
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.Doc,
+		&i.Rows,
+		&i.Progress,
+		&i.Error,
+		&i.WorkerID,
+		&i.Attempts,
+		&i.CreatedAt,
+		&i.StartedAt,
+		&i.HeartbeatAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
 const ingestALPR = `-- name: IngestALPR :one
 select alpr_util.ingest_alpr($1::jsonb) as result
 `
@@ -185,6 +449,144 @@ func (q *Queries) InsertHotlist(ctx context.Context, doc []byte) (int32, error)
 	return added, err
 }
 
+const listCameras = `-- name: ListCameras :many
+select camera_name, source_id, first_seen, last_seen, read_count,
+  case when location is not null then jsonb_build_object('lat', trunc(st_y(location)::numeric, 5), 'lon', trunc(st_x(location)::numeric, 5)) end as location
+from cameras
+where ($1::text = '' or camera_name ilike $1::text)
+order by camera_name
+`
+
+type ListCamerasRow struct {
+	CameraName string           `json:"cameraName"`
+	SourceID   pgtype.Text      `json:"sourceID"`
+	FirstSeen  pgtype.Timestamp `json:"firstSeen"`
+	LastSeen   pgtype.Timestamp `json:"lastSeen"`
+	ReadCount  int64            `json:"readCount"`
+	Location   []byte           `json:"location"`
+}
+
+func (q *Queries) ListCameras(ctx context.Context, pattern string) ([]ListCamerasRow, error) {
+	rows, err := q.db.Query(ctx, listCameras, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCamerasRow{}
+	for rows.Next() {
+		var i ListCamerasRow
+		if err := rows.Scan(
+			&i.CameraName,
+			&i.SourceID,
+			&i.FirstSeen,
+			&i.LastSeen,
+			&i.ReadCount,
+			&i.Location,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeadletter = `-- name: ListDeadletter :many
+select id from public.alpr_deadletter
+where ($1::text = '' or stage = $1::text)
+  and failed_at >= $2::timestamptz
+order by id
+limit $3::integer
+`
+
+type ListDeadletterParams struct {
+	Stage   string             `json:"stage"`
+	Since   pgtype.Timestamptz `json:"since"`
+	MaxRows int32              `json:"maxRows"`
+}
+
+func (q *Queries) ListDeadletter(ctx context.Context, arg ListDeadletterParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listDeadletter, arg.Stage, arg.Since, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLegalHolds = `-- name: ListLegalHolds :many
+select alpr_util.legal_hold_json(id, false) as hold
+from legal_holds
+where (not $1::boolean or released_at is null)
+order by created_at desc
+`
+
+func (q *Queries) ListLegalHolds(ctx context.Context, activeOnly bool) ([][]byte, error) {
+	rows, err := q.db.Query(ctx, listLegalHolds, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := [][]byte{}
+	for rows.Next() {
+		var hold []byte
+		if err := rows.Scan(&hold); err != nil {
+			return nil, err
+		}
+		items = append(items, hold)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSearchJobResults = `-- name: ListSearchJobResults :many
+select record from search_job_results
+where job_id = $1::bigint and seq > $2::bigint
+order by seq
+limit $3::integer
+`
+
+type ListSearchJobResultsParams struct {
+	JobID    int64 `json:"jobID"`
+	AfterSeq int64 `json:"afterSeq"`
+	MaxRows  int32 `json:"maxRows"`
+}
+
+func (q *Queries) ListSearchJobResults(ctx context.Context, arg ListSearchJobResultsParams) ([][]byte, error) {
+	rows, err := q.db.Query(ctx, listSearchJobResults, arg.JobID, arg.AfterSeq, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := [][]byte{}
+	for rows.Next() {
+		var record []byte
+		if err := rows.Scan(&record); err != nil {
+			return nil, err
+		}
+		items = append(items, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const nextWake = `-- name: NextWake :one
 
 
@@ -217,6 +619,44 @@ func (q *Queries) ReclaimStuck(ctx context.Context) (int32, error) {
 	return alerts_reclaim_stuck, err
 }
 
+const refreshCameras = `-- name: RefreshCameras :one
+select alpr_util.cameras_refresh()::bigint as cameras
+`
+
+func (q *Queries) RefreshCameras(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, refreshCameras)
+	var cameras int64
+	err := row.Scan(&cameras)
+	return cameras, err
+}
+
+const releaseLegalHold = `-- name: ReleaseLegalHold :one
+select alpr_util.legal_hold_release($1::bigint, $2::text)::boolean as released
+`
+
+type ReleaseLegalHoldParams struct {
+	ID         int64  `json:"id"`
+	ReleasedBy string `json:"releasedBy"`
+}
+
+func (q *Queries) ReleaseLegalHold(ctx context.Context, arg ReleaseLegalHoldParams) (bool, error) {
+	row := q.db.QueryRow(ctx, releaseLegalHold, arg.ID, arg.ReleasedBy)
+	var released bool
+	err := row.Scan(&released)
+	return released, err
+}
+
+const reprocessDeadletter = `-- name: ReprocessDeadletter :one
+select public.reprocess_deadletter($1::bigint) as result
+`
+
+func (q *Queries) ReprocessDeadletter(ctx context.Context, id int64) (string, error) {
+	row := q.db.QueryRow(ctx, reprocessDeadletter, id)
+	var result string
+	err := row.Scan(&result)
+	return result, err
+}
+
 const scheduleFailure = `-- name: ScheduleFailure :exec
 select alpr_util.hotlist_alert_schedule_failure($1, $2)
 `
@@ -239,3 +679,43 @@ func (q *Queries) ScheduleSuccess(ctx context.Context, id int64) error {
 	_, err := q.db.Exec(ctx, scheduleSuccess, id)
 	return err
 }
+
+const searchJobHeartbeat = `-- name: SearchJobHeartbeat :one
+update search_jobs set heartbeat_at = now()
+where id = $1::bigint and worker_id = $2::text
+returning status
+`
+
+type SearchJobHeartbeatParams struct {
+	ID       int64  `json:"id"`
+	WorkerID string `json:"workerID"`
+}
+
+func (q *Queries) SearchJobHeartbeat(ctx context.Context, arg SearchJobHeartbeatParams) (string, error) {
+	row := q.db.QueryRow(ctx, searchJobHeartbeat, arg.ID, arg.WorkerID)
+	var status string
+	err := row.Scan(&status)
+	return status, err
+}
+
+const updateSearchJobProgress = `-- name: UpdateSearchJobProgress :exec
+update search_jobs set rows = $1::bigint, progress = $2::float8, heartbeat_at = now()
+where id = $3::bigint and worker_id = $4::text and status = 'running'
+`
+
+type UpdateSearchJobProgressParams struct {
+	Rows     int64         `json:"rows"`
+	Progress pgtype.Float8 `json:"progress"`
+	ID       int64         `json:"id"`
+	WorkerID string        `json:"workerID"`
+}
+
+func (q *Queries) UpdateSearchJobProgress(ctx context.Context, arg UpdateSearchJobProgressParams) error {
+	_, err := q.db.Exec(ctx, updateSearchJobProgress,
+		arg.Rows,
+		arg.Progress,
+		arg.ID,
+		arg.WorkerID,
+	)
+	return err
+}
@@ -0,0 +1,127 @@
+package leader
+
+/* Leader election for jobs that must run in one process across all replicas (reclaimer, retention, camera refresh,
+event dispatcher). Every instance calls Run for the job, the one holding the job's postgres advisory lock runs it,
+the others wait and retry. The lock is a session lock held on a connection of the job's own, opened next to
+the pool rather than taken from it, so leading every job doesn't starve searches and the alert worker of
+pool connections. if that connection dies postgres drops the lock and another instance takes over.
+*/
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// advisory lock keys, one per singleton job. 42 is the alert scheduler's and 7_363_001 is migrations'.
+const (
+	KeyReclaimer  int64 = 7_363_002
+	KeyRetention  int64 = 7_363_003
+	KeyDispatcher int64 = 7_363_004
+	KeySearchJobs int64 = 7_363_005
+	KeyCameras    int64 = 7_363_006
+)
+
+const DefaultRetry = 15 * time.Second
+
+type Job struct {
+	Name string
+	Key  int64
+	//Run blocks while we lead, returning once ctx is done. ctx is cancelled when leadership is lost.
+	Run func(ctx context.Context)
+}
+
+// Start runs job under its lock in the background until ctx is done, connecting with the pool's settings.
+func Start(ctx context.Context, pool *pgxpool.Pool, retry time.Duration, job Job) {
+	go Run(ctx, pool.Config().ConnConfig, retry, job)
+}
+
+// Run tries for the job's lock every retry and runs the job while holding it. Blocks until ctx is done.
+// the lock connection is kept between tries and only reopened once it fails.
+func Run(ctx context.Context, connConfig *pgx.ConnConfig, retry time.Duration, job Job) {
+	if retry <= 0 {
+		retry = DefaultRetry
+	}
+	var conn *pgx.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close(context.Background())
+		}
+	}()
+	for {
+		var err error
+		if conn == nil {
+			conn, err = pgx.ConnectConfig(ctx, connConfig.Copy())
+		}
+		if err == nil {
+			if err = lead(ctx, conn, retry, job); err != nil {
+				//a failed session may still hold the lock, closing it is what lets it go
+				conn.Close(context.Background())
+				conn = nil
+			}
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("leader %s: %v\n", job.Name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+// lead runs the job if the lock is free, returning when the job is done or the lock's connection is lost.
+// an error means conn can't be trusted anymore.
+func lead(ctx context.Context, conn *pgx.Conn, retry time.Duration, job Job) error {
+	var locked bool
+	if err := conn.QueryRow(ctx, "select pg_try_advisory_lock($1)", job.Key).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+	log.Printf("leader %s: acquired, running here\n", job.Name)
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job.Run(jobCtx)
+	}()
+
+	//the lock lives as long as the connection, keep checking it's still there
+	ticker := time.NewTicker(retry)
+	defer ticker.Stop()
+	var lost error
+watch:
+	for {
+		select {
+		case <-done:
+			break watch
+		case <-ctx.Done():
+			break watch
+		case <-ticker.C:
+			if lost = conn.Ping(ctx); lost != nil {
+				break watch
+			}
+		}
+	}
+	cancel()
+	<-done
+
+	if lost != nil {
+		return fmt.Errorf("lost the lock connection: %w", lost)
+	}
+	unlockCtx, unlockCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer unlockCancel()
+	if _, err := conn.Exec(unlockCtx, "select pg_advisory_unlock($1)", job.Key); err != nil {
+		return err
+	}
+	log.Printf("leader %s: released\n", job.Name)
+	return nil
+}
@@ -0,0 +1,229 @@
+package migrate
+
+/* Versioned schema migrations, embedded in the binary.
+Files in migrations/ are NNNN_name.up.sql with an optional NNNN_name.down.sql, applied in version order.
+Each one runs in its own transaction and is recorded in schema_migrations, so a failed migration leaves
+nothing half applied. A session advisory lock keeps two instances starting at once from both migrating.
+
+The service refuses to start against a schema older than Expected(), run `alpr_service migrate up` first.
+A newer schema is allowed so an old binary keeps running during a rollout.
+
+Never edit a migration that has shipped, add a new one.
+*/
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var embedded embed.FS
+
+// arbitrary, only has to be unique among our advisory locks (42 is the alert scheduler's)
+const lockKey = 7_363_001
+
+var ErrSchemaOutdated = errors.New("database schema is older than this build expects")
+
+var fileRe = regexp.MustCompile(`^(\d{4})_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string //empty when the migration can't be reverted
+}
+
+// Load reads the migrations from fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := fileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration file %s doesn't match NNNN_name.up.sql / NNNN_name.down.sql", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %04d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be 1..n without gaps, found %04d at position %d", m.Version, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// Embedded returns the migrations compiled into this binary.
+func Embedded() []Migration {
+	migrations, err := Load(embedded)
+	if err != nil {
+		panic(err) //a bad file name is a build mistake, migrate_test.go catches it first
+	}
+	return migrations
+}
+
+// Expected is the schema version this build is written against.
+func Expected() int {
+	migrations := Embedded()
+	return migrations[len(migrations)-1].Version
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+func New(pool *pgxpool.Pool) *Migrator {
+	return &Migrator{pool: pool, migrations: Embedded()}
+}
+
+const createTable = `
+CREATE TABLE IF NOT EXISTS public.schema_migrations (
+  version    int primary key,
+  name       text not null,
+  applied_at timestamptz not null default now()
+)`
+
+// Version is the highest applied migration, 0 for a database that has never been migrated.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var exists bool
+	if err := m.pool.QueryRow(ctx, `SELECT to_regclass('public.schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+	var version int
+	err := m.pool.QueryRow(ctx, `SELECT coalesce(max(version), 0) FROM public.schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// Check is the startup check: an error if the schema is older than this build expects.
+func (m *Migrator) Check(ctx context.Context) (int, error) {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	if version < Expected() {
+		return version, fmt.Errorf("%w: database is at %d, this build needs %d. run `migrate up`", ErrSchemaOutdated, version, Expected())
+	}
+	return version, nil
+}
+
+// Up applies every pending migration, or up to target when target > 0. Returns the ones applied.
+func (m *Migrator) Up(ctx context.Context, target int) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn, current int) error {
+		for _, mig := range m.migrations {
+			if mig.Version <= current || (target > 0 && mig.Version > target) {
+				continue
+			}
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, mig.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `INSERT INTO public.schema_migrations(version, name) VALUES ($1, $2)`, mig.Version, mig.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps migrations, newest first. Returns the ones reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn, current int) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := m.migrations[i]
+			if mig.Version > current {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %04d_%s can't be reverted, it has no down file", mig.Version, mig.Name)
+			}
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, mig.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `DELETE FROM public.schema_migrations WHERE version = $1`, mig.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("reverting %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// locked runs fn on one connection holding the migration lock, with the current version.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn, current int) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("taking migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if _, err := conn.Exec(ctx, createTable); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	var current int
+	if err := conn.QueryRow(ctx, `SELECT coalesce(max(version), 0) FROM public.schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+	return fn(conn, current)
+}
@@ -0,0 +1,67 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Load(embedded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Expected() != len(migrations) {
+		t.Errorf("Expected() = %d with %d migrations", Expected(), len(migrations))
+	}
+	for _, m := range migrations {
+		//reverting the baseline would drop every plate read, it's irreversible on purpose
+		if m.Version == 1 && m.Down != "" {
+			t.Errorf("%04d_%s has a down migration, the baseline must not be revertible", m.Version, m.Name)
+		}
+		if m.Version > 1 && m.Down == "" {
+			t.Errorf("%04d_%s has no down migration", m.Version, m.Name)
+		}
+		//migrations run in a transaction, these can't
+		if strings.Contains(strings.ToLower(m.Up), "concurrently") {
+			t.Errorf("%04d_%s uses CONCURRENTLY, which can't run inside the migration transaction", m.Version, m.Name)
+		}
+	}
+}
+
+func TestLoadRejectsBadSets(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"bad name": {
+			"migrations/1_init.up.sql": {Data: []byte("select 1")},
+		},
+		"gap": {
+			"migrations/0001_init.up.sql": {Data: []byte("select 1")},
+			"migrations/0003_next.up.sql": {Data: []byte("select 1")},
+		},
+		"down without up": {
+			"migrations/0001_init.down.sql": {Data: []byte("select 1")},
+		},
+		"two names": {
+			"migrations/0001_init.up.sql":    {Data: []byte("select 1")},
+			"migrations/0001_other.down.sql": {Data: []byte("select 1")},
+		},
+	}
+	for name, fsys := range tests {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: loaded without error", name)
+		}
+	}
+
+	good := fstest.MapFS{
+		"migrations/0002_b.up.sql":   {Data: []byte("select 2")},
+		"migrations/0001_a.up.sql":   {Data: []byte("select 1")},
+		"migrations/0001_a.down.sql": {Data: []byte("select -1")},
+	}
+	migrations, err := Load(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "a" || migrations[0].Down != "select -1" || migrations[1].Down != "" {
+		t.Errorf("loaded %+v", migrations)
+	}
+}
@@ -1,3 +1,8 @@
+-- 0001 baseline: the schema as it was applied by hand from sql/schema.sql.
+-- Made re-runnable so it can be applied over a database that already has it:
+--   parse_unixtime is created in alpr_util, where the ingest trigger has always called it
+--   alert_on_hotlist_match is create or replace, trg_check_hotlist is dropped first
+
 -- 1) Extensions (ensure shared_preload_libraries includes 'timescaledb' on the server)
 CREATE EXTENSION IF NOT EXISTS timescaledb;
 CREATE EXTENSION IF NOT EXISTS timescaledb_toolkit;
@@ -54,7 +59,7 @@ COMMENT ON COLUMN public.alpr.read_id  IS 'unique id of the read scan';
 COMMENT ON COLUMN public.alpr.vehicle_type IS 'sedan, suv, etc';
 
 
-create function alpr_util.alert_on_hotlist_match() returns trigger
+create or replace function alpr_util.alert_on_hotlist_match() returns trigger
     language plpgsql
 as
 $$
@@ -77,6 +82,7 @@ begin
 end$$;
 
 
+drop trigger if exists trg_check_hotlist on alpr;
 create trigger trg_check_hotlist
     after insert
     on alpr
@@ -99,7 +105,7 @@ LANGUAGE sql IMMUTABLE AS $$
 $$;
 
 -- Timestamp parser: ISO8601 string or numeric epoch seconds
-create function parse_unixtime(val jsonb) returns timestamp with time zone
+create or replace function alpr_util.parse_unixtime(val jsonb) returns timestamp with time zone
     immutable
     language plpgsql
 as
@@ -0,0 +1,51 @@
+-- back to ingest without dedupe
+CREATE OR REPLACE FUNCTION alpr_util.ingest_alpr(p_doc JSONB)
+RETURNS TEXT LANGUAGE plpgsql SECURITY DEFINER AS $$
+DECLARE
+  v_id BIGINT;
+  v_sqlstate TEXT; v_msg TEXT; v_detail TEXT; v_hint TEXT; v_ctx TEXT;
+BEGIN
+  BEGIN
+    INSERT INTO public.alpr_ingest(doc) VALUES (p_doc) RETURNING id INTO v_id;
+  EXCEPTION WHEN OTHERS THEN
+    GET STACKED DIAGNOSTICS v_sqlstate = returned_sqlstate,
+                            v_msg      = message_text,
+                            v_detail   = pg_exception_detail,
+                            v_hint     = pg_exception_hint,
+                            v_ctx      = pg_exception_context;
+    INSERT INTO public.alpr_deadletter(stage, sqlstate, message, detail, hint, context, doc)
+    VALUES ('staging', v_sqlstate, v_msg, v_detail, v_hint, v_ctx, p_doc);
+    RETURN 'deadletter:staging';
+  END;
+
+  BEGIN
+    INSERT INTO public.alpr (
+      doc, inserted_at, plate_num, read_time, camera_name, plate_code,
+      image_id, location, read_id, make, vehicle_type, color
+    )
+    SELECT
+      doc, now(), plate_num, read_time, camera_name, plate_code,
+      image_id, location, read_id, make, vehicle_type, color
+    FROM public.alpr_ingest WHERE id = v_id;
+
+    -- NOT Sure about this here
+    DELETE FROM public.alpr_ingest WHERE id = v_id;
+    RETURN 'ok:alpr-ingest';
+
+  EXCEPTION WHEN OTHERS THEN
+    GET STACKED DIAGNOSTICS v_sqlstate = returned_sqlstate,
+                            v_msg      = message_text,
+                            v_detail   = pg_exception_detail,
+                            v_hint     = pg_exception_hint,
+                            v_ctx      = pg_exception_context;
+    INSERT INTO public.alpr_deadletter(stage, sqlstate, message, detail, hint, context, doc)
+    SELECT 'alpr-insert', v_sqlstate, v_msg, v_detail, v_hint, v_ctx, doc
+    FROM public.alpr_ingest WHERE id = v_id;
+    RETURN 'deadletter:alpr-insert';
+  END;
+
+  -- I think this should be where the alert goes, if we're here we know the alpr insert succeeded
+
+END $$;
+
+DROP INDEX IF EXISTS public.alpr_read_id_read_time_uidx;
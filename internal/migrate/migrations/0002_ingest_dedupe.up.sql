@@ -0,0 +1,76 @@
+-- Idempotent ingest: PlateSmart retries can deliver the same read more than once.
+-- Unique indexes on a hypertable must include the partition column, so the key is (read_id, read_time).
+-- Reads without a read_id can't be deduped and are always inserted (NULLs never conflict).
+-- Clear out duplicates that got in before this index existed, keeping the first row.
+DELETE FROM public.alpr a
+USING public.alpr b
+WHERE a.read_id = b.read_id AND a.read_time = b.read_time AND a.id > b.id
+  AND NOT EXISTS (SELECT 1 FROM pg_indexes WHERE indexname = 'alpr_read_id_read_time_uidx');
+CREATE UNIQUE INDEX IF NOT EXISTS alpr_read_id_read_time_uidx ON public.alpr (read_id, read_time);
+
+-- Returns '<outcome>:<stage>[:<alpr id>]'
+--   ok:alpr-ingest:<id>         new row inserted
+--   duplicate:alpr-ingest:<id>  read_id/read_time already stored, <id> is the existing row. no second hotlist alert fires.
+--   deadletter:staging | deadletter:alpr-insert
+CREATE OR REPLACE FUNCTION alpr_util.ingest_alpr(p_doc JSONB)
+RETURNS TEXT LANGUAGE plpgsql SECURITY DEFINER AS $$
+DECLARE
+  v_id BIGINT;
+  v_alpr_id BIGINT;
+  v_sqlstate TEXT; v_msg TEXT; v_detail TEXT; v_hint TEXT; v_ctx TEXT;
+BEGIN
+  BEGIN
+    INSERT INTO public.alpr_ingest(doc) VALUES (p_doc) RETURNING id INTO v_id;
+  EXCEPTION WHEN OTHERS THEN
+    GET STACKED DIAGNOSTICS v_sqlstate = returned_sqlstate,
+                            v_msg      = message_text,
+                            v_detail   = pg_exception_detail,
+                            v_hint     = pg_exception_hint,
+                            v_ctx      = pg_exception_context;
+    INSERT INTO public.alpr_deadletter(stage, sqlstate, message, detail, hint, context, doc)
+    VALUES ('staging', v_sqlstate, v_msg, v_detail, v_hint, v_ctx, p_doc);
+    RETURN 'deadletter:staging';
+  END;
+
+  BEGIN
+    -- the hotlist trigger only fires for rows actually inserted, so a duplicate can't raise a second alert
+    INSERT INTO public.alpr (
+      doc, inserted_at, plate_num, read_time, camera_name, plate_code,
+      image_id, location, read_id, make, vehicle_type, color
+    )
+    SELECT
+      doc, now(), plate_num, read_time, camera_name, plate_code,
+      image_id, location, read_id, make, vehicle_type, color
+    FROM public.alpr_ingest WHERE id = v_id
+    ON CONFLICT (read_id, read_time) DO NOTHING
+    RETURNING id INTO v_alpr_id;
+
+    IF v_alpr_id IS NULL THEN
+      SELECT a.id INTO v_alpr_id
+      FROM public.alpr a
+      JOIN public.alpr_ingest i ON a.read_id = i.read_id AND a.read_time = i.read_time
+      WHERE i.id = v_id;
+
+      DELETE FROM public.alpr_ingest WHERE id = v_id;
+      RETURN 'duplicate:alpr-ingest:' || v_alpr_id;
+    END IF;
+
+    -- NOT Sure about this here
+    DELETE FROM public.alpr_ingest WHERE id = v_id;
+    RETURN 'ok:alpr-ingest:' || v_alpr_id;
+
+  EXCEPTION WHEN OTHERS THEN
+    GET STACKED DIAGNOSTICS v_sqlstate = returned_sqlstate,
+                            v_msg      = message_text,
+                            v_detail   = pg_exception_detail,
+                            v_hint     = pg_exception_hint,
+                            v_ctx      = pg_exception_context;
+    INSERT INTO public.alpr_deadletter(stage, sqlstate, message, detail, hint, context, doc)
+    SELECT 'alpr-insert', v_sqlstate, v_msg, v_detail, v_hint, v_ctx, doc
+    FROM public.alpr_ingest WHERE id = v_id;
+    RETURN 'deadletter:alpr-insert';
+  END;
+
+  -- I think this should be where the alert goes, if we're here we know the alpr insert succeeded
+
+END $$;
@@ -0,0 +1,114 @@
+-- back to the hand written extraction and the 0002 ingest
+CREATE OR REPLACE FUNCTION alpr_util.alpr_ingest_fill() RETURNS TRIGGER
+    LANGUAGE plpgsql
+AS $$
+BEGIN
+  IF jsonb_typeof(NEW.doc) <> 'object' THEN
+    RAISE EXCEPTION USING errcode='22023', message='doc must be a JSON object';
+  END IF;
+
+  NEW.plate_num    := COALESCE(NEW.plate_num,    NULLIF(btrim(NEW.doc->'plate'->>'tag'), ''));
+  NEW.camera_name  := COALESCE(NEW.camera_name,  NULLIF(btrim(NEW.doc->'source'->>'name'), ''));
+  NEW.plate_code   := COALESCE(NEW.plate_code,   NULLIF(btrim(NEW.doc->'plate'->>'code'), ''));
+  NEW.image_id     := COALESCE(NEW.image_id,     NULLIF(btrim(NEW.doc->'image'->>'id'), ''));
+  NEW.read_id      := COALESCE(NEW.read_id,      alpr_util.jtext(NEW.doc, 'id'));
+  NEW.make         := COALESCE(NEW.make,         NULLIF(btrim(NEW.doc->'vehicle'->'make'->>'name'), ''));
+  NEW.vehicle_type := COALESCE(NEW.vehicle_type, NULLIF(btrim(NEW.doc->'vehicle'->'type'->>'name'), ''));
+  NEW.color        := COALESCE(NEW.color,        NULLIF(btrim(NEW.doc->'color'->>'code'), ''));
+
+  IF NEW.read_time IS NULL THEN
+    NEW.read_time := alpr_util.parse_unixtime(NEW.doc->'timestamp');
+  END IF;
+
+  IF NEW.location IS NULL THEN
+    NEW.location := alpr_util.location_from_json(NEW.doc->'location');
+  END IF;
+
+  RETURN NEW;
+END $$;
+
+-- Returns '<outcome>:<stage>[:<alpr id>]'
+--   ok:alpr-ingest:<id>         new row inserted
+--   duplicate:alpr-ingest:<id>  read_id/read_time already stored, <id> is the existing row. no second hotlist alert fires.
+--   deadletter:staging | deadletter:alpr-insert
+CREATE OR REPLACE FUNCTION alpr_util.ingest_alpr(p_doc JSONB)
+RETURNS TEXT LANGUAGE plpgsql SECURITY DEFINER AS $$
+DECLARE
+  v_id BIGINT;
+  v_alpr_id BIGINT;
+  v_sqlstate TEXT; v_msg TEXT; v_detail TEXT; v_hint TEXT; v_ctx TEXT;
+BEGIN
+  BEGIN
+    INSERT INTO public.alpr_ingest(doc) VALUES (p_doc) RETURNING id INTO v_id;
+  EXCEPTION WHEN OTHERS THEN
+    GET STACKED DIAGNOSTICS v_sqlstate = returned_sqlstate,
+                            v_msg      = message_text,
+                            v_detail   = pg_exception_detail,
+                            v_hint     = pg_exception_hint,
+                            v_ctx      = pg_exception_context;
+    INSERT INTO public.alpr_deadletter(stage, sqlstate, message, detail, hint, context, doc)
+    VALUES ('staging', v_sqlstate, v_msg, v_detail, v_hint, v_ctx, p_doc);
+    RETURN 'deadletter:staging';
+  END;
+
+  BEGIN
+    -- the hotlist trigger only fires for rows actually inserted, so a duplicate can't raise a second alert
+    INSERT INTO public.alpr (
+      doc, inserted_at, plate_num, read_time, camera_name, plate_code,
+      image_id, location, read_id, make, vehicle_type, color
+    )
+    SELECT
+      doc, now(), plate_num, read_time, camera_name, plate_code,
+      image_id, location, read_id, make, vehicle_type, color
+    FROM public.alpr_ingest WHERE id = v_id
+    ON CONFLICT (read_id, read_time) DO NOTHING
+    RETURNING id INTO v_alpr_id;
+
+    IF v_alpr_id IS NULL THEN
+      SELECT a.id INTO v_alpr_id
+      FROM public.alpr a
+      JOIN public.alpr_ingest i ON a.read_id = i.read_id AND a.read_time = i.read_time
+      WHERE i.id = v_id;
+
+      DELETE FROM public.alpr_ingest WHERE id = v_id;
+      RETURN 'duplicate:alpr-ingest:' || v_alpr_id;
+    END IF;
+
+    -- NOT Sure about this here
+    DELETE FROM public.alpr_ingest WHERE id = v_id;
+    RETURN 'ok:alpr-ingest:' || v_alpr_id;
+
+  EXCEPTION WHEN OTHERS THEN
+    GET STACKED DIAGNOSTICS v_sqlstate = returned_sqlstate,
+                            v_msg      = message_text,
+                            v_detail   = pg_exception_detail,
+                            v_hint     = pg_exception_hint,
+                            v_ctx      = pg_exception_context;
+    INSERT INTO public.alpr_deadletter(stage, sqlstate, message, detail, hint, context, doc)
+    SELECT 'alpr-insert', v_sqlstate, v_msg, v_detail, v_hint, v_ctx, doc
+    FROM public.alpr_ingest WHERE id = v_id;
+    RETURN 'deadletter:alpr-insert';
+  END;
+
+  -- I think this should be where the alert goes, if we're here we know the alpr insert succeeded
+
+END $$;
+
+DROP FUNCTION IF EXISTS alpr_util.ingest_field_map_version();
+DROP TABLE IF EXISTS alpr_util.ingest_field_map;
+
+ALTER TABLE public.alpr
+  DROP COLUMN IF EXISTS image_width,
+  DROP COLUMN IF EXISTS image_height,
+  DROP COLUMN IF EXISTS plate_region,
+  DROP COLUMN IF EXISTS occlusion,
+  DROP COLUMN IF EXISTS source_type,
+  DROP COLUMN IF EXISTS field_map_version;
+
+ALTER TABLE public.alpr_ingest
+  DROP COLUMN IF EXISTS image_width,
+  DROP COLUMN IF EXISTS image_height,
+  DROP COLUMN IF EXISTS plate_region,
+  DROP COLUMN IF EXISTS occlusion,
+  DROP COLUMN IF EXISTS source_type,
+  DROP COLUMN IF EXISTS field_map_version;
@@ -0,0 +1,180 @@
+-- =========================================================
+-- 2a) Field mapping: which doc path fills which column.
+-- Versioned so a mapping change is a new version, never an edit. the highest version is the active one,
+-- and each staged row records the version that filled it.
+-- Keep the active version in sync with plates.FieldMap, plates/fieldmap_test.go checks this file.
+-- A new mapping version goes in a new migration.
+-- =========================================================
+ALTER TABLE public.alpr_ingest
+  ADD COLUMN IF NOT EXISTS image_width       INT,
+  ADD COLUMN IF NOT EXISTS image_height      INT,
+  ADD COLUMN IF NOT EXISTS plate_region      JSONB,   -- {x, y, width, height} of the plate in the image
+  ADD COLUMN IF NOT EXISTS occlusion         REAL,
+  ADD COLUMN IF NOT EXISTS source_type       TEXT,
+  ADD COLUMN IF NOT EXISTS field_map_version INT;
+
+ALTER TABLE public.alpr
+  ADD COLUMN IF NOT EXISTS image_width       int,
+  ADD COLUMN IF NOT EXISTS image_height      int,
+  ADD COLUMN IF NOT EXISTS plate_region      jsonb,
+  ADD COLUMN IF NOT EXISTS occlusion         real,
+  ADD COLUMN IF NOT EXISTS source_type       varchar,
+  ADD COLUMN IF NOT EXISTS field_map_version int;
+
+CREATE TABLE IF NOT EXISTS alpr_util.ingest_field_map (
+  version     INT    NOT NULL,
+  column_name TEXT   NOT NULL,
+  doc_path    TEXT[] NOT NULL,
+  PRIMARY KEY (version, column_name)
+);
+
+-- version 1: the original hand written extraction. color was read from the wrong path, kept for reference.
+INSERT INTO alpr_util.ingest_field_map(version, column_name, doc_path) VALUES
+  (1, 'plate_num',    '{plate,tag}'),
+  (1, 'camera_name',  '{source,name}'),
+  (1, 'plate_code',   '{plate,code}'),
+  (1, 'image_id',     '{image,id}'),
+  (1, 'read_id',      '{id}'),
+  (1, 'make',         '{vehicle,make,name}'),
+  (1, 'vehicle_type', '{vehicle,type,name}'),
+  (1, 'color',        '{color,code}')
+ON CONFLICT DO NOTHING;
+
+-- version 2: color from vehicle.color.code, plus image size, plate region, occlusion and source type.
+INSERT INTO alpr_util.ingest_field_map(version, column_name, doc_path) VALUES
+  (2, 'plate_num',    '{plate,tag}'),
+  (2, 'camera_name',  '{source,name}'),
+  (2, 'plate_code',   '{plate,code}'),
+  (2, 'image_id',     '{image,id}'),
+  (2, 'read_id',      '{id}'),
+  (2, 'make',         '{vehicle,make,name}'),
+  (2, 'vehicle_type', '{vehicle,type,name}'),
+  (2, 'color',        '{vehicle,color,code}'),
+  (2, 'image_width',  '{image,width}'),
+  (2, 'image_height', '{image,height}'),
+  (2, 'plate_region', '{plate,region}'),
+  (2, 'occlusion',    '{vehicle,occlusion}'),
+  (2, 'source_type',  '{source,type}')
+ON CONFLICT DO NOTHING;
+
+-- reads stored under version 1 never got a color, backfill it from the doc.
+UPDATE public.alpr
+SET color = NULLIF(btrim(doc->'vehicle'->'color'->>'code'), '')
+WHERE color IS NULL AND field_map_version IS NULL
+  AND doc->'vehicle'->'color'->>'code' IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION alpr_util.ingest_field_map_version()
+RETURNS INT
+LANGUAGE sql STABLE AS $$
+  SELECT max(version) FROM alpr_util.ingest_field_map
+$$;
+
+CREATE OR REPLACE FUNCTION alpr_util.alpr_ingest_fill() RETURNS TRIGGER
+    LANGUAGE plpgsql
+AS $$
+DECLARE
+  v_version INT;
+  v_fields  JSONB;
+BEGIN
+  IF jsonb_typeof(NEW.doc) <> 'object' THEN
+    RAISE EXCEPTION USING errcode='22023', message='doc must be a JSON object';
+  END IF;
+
+  v_version := COALESCE(NEW.field_map_version, alpr_util.ingest_field_map_version());
+
+  -- pull every mapped path out of the doc. strings are trimmed and blanks count as missing.
+  SELECT jsonb_object_agg(m.column_name, v.val)
+    INTO v_fields
+  FROM alpr_util.ingest_field_map m
+  CROSS JOIN LATERAL (SELECT NEW.doc #> m.doc_path AS raw) r
+  CROSS JOIN LATERAL (
+    SELECT CASE WHEN jsonb_typeof(r.raw) = 'string' THEN to_jsonb(NULLIF(btrim(r.raw #>> '{}'), ''))
+                ELSE r.raw END AS val
+  ) v
+  WHERE m.version = v_version AND v.val IS NOT NULL AND v.val <> 'null'::jsonb;
+
+  -- values already set on the row win over the doc (same as the old COALESCE(NEW.col, ...)).
+  -- a value that won't cast to its column raises here and the read is dead-lettered at staging.
+  v_fields := COALESCE(v_fields, '{}'::jsonb) || jsonb_strip_nulls(to_jsonb(NEW) - 'doc' - 'location');
+  NEW := jsonb_populate_record(NEW, v_fields);
+  NEW.field_map_version := v_version;
+
+  IF NEW.read_time IS NULL THEN
+    NEW.read_time := alpr_util.parse_unixtime(NEW.doc->'timestamp');
+  END IF;
+
+  IF NEW.location IS NULL THEN
+    NEW.location := alpr_util.location_from_json(NEW.doc->'location');
+  END IF;
+
+  RETURN NEW;
+END $$;
+
+-- Returns '<outcome>:<stage>[:<alpr id>]'
+--   ok:alpr-ingest:<id>         new row inserted
+--   duplicate:alpr-ingest:<id>  read_id/read_time already stored, <id> is the existing row. no second hotlist alert fires.
+--   deadletter:staging | deadletter:alpr-insert
+CREATE OR REPLACE FUNCTION alpr_util.ingest_alpr(p_doc JSONB)
+RETURNS TEXT LANGUAGE plpgsql SECURITY DEFINER AS $$
+DECLARE
+  v_id BIGINT;
+  v_alpr_id BIGINT;
+  v_sqlstate TEXT; v_msg TEXT; v_detail TEXT; v_hint TEXT; v_ctx TEXT;
+BEGIN
+  BEGIN
+    INSERT INTO public.alpr_ingest(doc) VALUES (p_doc) RETURNING id INTO v_id;
+  EXCEPTION WHEN OTHERS THEN
+    GET STACKED DIAGNOSTICS v_sqlstate = returned_sqlstate,
+                            v_msg      = message_text,
+                            v_detail   = pg_exception_detail,
+                            v_hint     = pg_exception_hint,
+                            v_ctx      = pg_exception_context;
+    INSERT INTO public.alpr_deadletter(stage, sqlstate, message, detail, hint, context, doc)
+    VALUES ('staging', v_sqlstate, v_msg, v_detail, v_hint, v_ctx, p_doc);
+    RETURN 'deadletter:staging';
+  END;
+
+  BEGIN
+    -- the hotlist trigger only fires for rows actually inserted, so a duplicate can't raise a second alert
+    INSERT INTO public.alpr (
+      doc, inserted_at, plate_num, read_time, camera_name, plate_code,
+      image_id, location, read_id, make, vehicle_type, color,
+      image_width, image_height, plate_region, occlusion, source_type, field_map_version
+    )
+    SELECT
+      doc, now(), plate_num, read_time, camera_name, plate_code,
+      image_id, location, read_id, make, vehicle_type, color,
+      image_width, image_height, plate_region, occlusion, source_type, field_map_version
+    FROM public.alpr_ingest WHERE id = v_id
+    ON CONFLICT (read_id, read_time) DO NOTHING
+    RETURNING id INTO v_alpr_id;
+
+    IF v_alpr_id IS NULL THEN
+      SELECT a.id INTO v_alpr_id
+      FROM public.alpr a
+      JOIN public.alpr_ingest i ON a.read_id = i.read_id AND a.read_time = i.read_time
+      WHERE i.id = v_id;
+
+      DELETE FROM public.alpr_ingest WHERE id = v_id;
+      RETURN 'duplicate:alpr-ingest:' || v_alpr_id;
+    END IF;
+
+    -- NOT Sure about this here
+    DELETE FROM public.alpr_ingest WHERE id = v_id;
+    RETURN 'ok:alpr-ingest:' || v_alpr_id;
+
+  EXCEPTION WHEN OTHERS THEN
+    GET STACKED DIAGNOSTICS v_sqlstate = returned_sqlstate,
+                            v_msg      = message_text,
+                            v_detail   = pg_exception_detail,
+                            v_hint     = pg_exception_hint,
+                            v_ctx      = pg_exception_context;
+    INSERT INTO public.alpr_deadletter(stage, sqlstate, message, detail, hint, context, doc)
+    SELECT 'alpr-insert', v_sqlstate, v_msg, v_detail, v_hint, v_ctx, doc
+    FROM public.alpr_ingest WHERE id = v_id;
+    RETURN 'deadletter:alpr-insert';
+  END;
+
+  -- I think this should be where the alert goes, if we're here we know the alpr insert succeeded
+
+END $$;
@@ -0,0 +1,2 @@
+drop index if exists idx_hotlist_alert_events_created_at;
+drop table if exists retention_audit;
@@ -0,0 +1,20 @@
+-- =========================
+-- Retention: one row per table per purge run (internal/api/retention)
+-- =========================
+create table if not exists retention_audit (
+  id            bigserial primary key,
+  table_name    text not null,
+  method        text not null,          -- drop_chunks | batch_delete
+  cutoff        timestamptz not null,   -- rows older than this were purged
+  dry_run       boolean not null,
+  chunks        bigint not null default 0,
+  rows_affected bigint not null default 0, -- estimated for drop_chunks
+  error         text,
+  started_at    timestamptz not null,
+  finished_at   timestamptz not null default now(),
+  details       jsonb
+);
+create index if not exists idx_retention_audit_finished_at on retention_audit (finished_at desc);
+
+-- batch deletes walk these
+create index if not exists idx_hotlist_alert_events_created_at on hotlist_alert_events (created_at);
@@ -0,0 +1,7 @@
+drop function if exists alpr_util.legal_hold_json(bigint, boolean);
+drop function if exists alpr_util.legal_hold_release(bigint, text);
+drop function if exists alpr_util.legal_hold_create(jsonb);
+drop function if exists alpr_util.legal_hold_capture(bigint, timestamp);
+drop table if exists legal_hold_reads;
+drop table if exists legal_hold_criteria;
+drop table if exists legal_holds;
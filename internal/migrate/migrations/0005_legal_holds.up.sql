@@ -0,0 +1,132 @@
+-- =========================
+-- Legal holds: reads kept as evidence past retention (internal/api/holds)
+-- A hold is a case number plus criteria. Matching alpr rows are copied into legal_hold_reads,
+-- a plain table outside the hypertable, so drop_chunks can't take them. The purge job captures
+-- again right before it drops chunks, which picks up reads that arrived after the hold was created.
+-- =========================
+create table if not exists legal_holds (
+  id           bigserial primary key,
+  case_number  text not null,
+  description  text,
+  created_by   text,
+  created_at   timestamptz not null default now(),
+  released_at  timestamptz,               -- null while the hold is active
+  released_by  text
+);
+create index if not exists idx_legal_holds_case_number on legal_holds (case_number);
+
+-- one row per thing held. read_id and plate_num may each be combined with a window, a window alone holds every read in it.
+create table if not exists legal_hold_criteria (
+  id          bigserial primary key,
+  hold_id     bigint not null references legal_holds(id) on delete cascade,
+  read_id     text,
+  plate_num   text,
+  start_time  timestamp,   -- same clock as alpr.read_time (UTC, no zone)
+  end_time    timestamp,
+  constraint criteria_not_empty check (read_id is not null or plate_num is not null or start_time is not null),
+  constraint criteria_window_ok check ((start_time is null) = (end_time is null) and (start_time is null or start_time < end_time))
+);
+create index if not exists idx_legal_hold_criteria_hold_id on legal_hold_criteria (hold_id);
+
+create table if not exists legal_hold_reads (
+  hold_id      bigint not null references legal_holds(id) on delete cascade,
+  alpr_id      bigint not null,
+  read_time    timestamp not null,
+  read_id      text,
+  plate_num    text,
+  camera_name  text,
+  image_keys   text[] not null default '{}',  -- wasabi keys of the vehicle and plate images
+  alpr_row     jsonb not null,                -- the alpr row as it was when captured
+  held_at      timestamptz not null default now(),
+  primary key (hold_id, alpr_id, read_time)
+);
+create index if not exists idx_legal_hold_reads_read_id on legal_hold_reads (read_id);
+create index if not exists idx_legal_hold_reads_plate_num on legal_hold_reads (plate_num);
+
+-- copy every alpr row matching an active hold into legal_hold_reads.
+-- p_hold_id limits it to one hold, p_before to reads older than that (what the purge is about to drop).
+create or replace function alpr_util.legal_hold_capture(p_hold_id bigint default null, p_before timestamp default null)
+returns bigint language plpgsql as $$
+declare n bigint;
+begin
+  insert into legal_hold_reads(hold_id, alpr_id, read_time, read_id, plate_num, camera_name, image_keys, alpr_row)
+  select distinct on (c.hold_id, a.id, a.read_time)
+    c.hold_id, a.id, a.read_time, a.read_id, a.plate_num, a.camera_name,
+    -- same keys the search and alert presigning use
+    array_remove(array[
+      case when a.image_id is not null and a.doc->'source'->>'id' is not null
+           then 'alpr/' || (a.doc->'source'->>'id') || '/' || a.image_id end,
+      case when a.read_id is not null and a.doc->'source'->>'id' is not null
+           then 'alpr-plate/' || (a.doc->'source'->>'id') || '/' || a.read_id end
+    ], null),
+    to_jsonb(a)
+  from legal_hold_criteria c
+  join legal_holds h on h.id = c.hold_id and h.released_at is null
+  join public.alpr a
+    on (c.read_id is null or a.read_id = c.read_id)
+   and (c.plate_num is null or a.plate_num ilike c.plate_num)  -- ilike so the trigram index can be used
+   and (c.start_time is null or (a.read_time >= c.start_time and a.read_time < c.end_time))
+  where (p_hold_id is null or h.id = p_hold_id)
+    and (p_before is null or a.read_time < p_before)
+  on conflict do nothing;
+
+  get diagnostics n = row_count;
+  return n;
+end$$;
+
+-- create a hold from {case_number, description, created_by, criteria:[{read_id, plate_num, start_time, end_time}]}
+-- and capture what it matches right away. returns the hold id.
+create or replace function alpr_util.legal_hold_create(p_doc jsonb)
+returns bigint language plpgsql as $$
+declare v_id bigint;
+begin
+  insert into legal_holds(case_number, description, created_by)
+  values (p_doc->>'case_number', p_doc->>'description', p_doc->>'created_by')
+  returning id into v_id;
+
+  insert into legal_hold_criteria(hold_id, read_id, plate_num, start_time, end_time)
+  select v_id, c->>'read_id', c->>'plate_num', (c->>'start_time')::timestamptz at time zone 'UTC', (c->>'end_time')::timestamptz at time zone 'UTC'
+  from jsonb_array_elements(p_doc->'criteria') c;
+
+  perform alpr_util.legal_hold_capture(v_id);
+  return v_id;
+end$$;
+
+-- release a hold. its copies are deleted, the reads are back under normal retention.
+create or replace function alpr_util.legal_hold_release(p_id bigint, p_by text)
+returns boolean language plpgsql as $$
+begin
+  update legal_holds set released_at = now(), released_by = p_by
+  where id = p_id and released_at is null;
+  if not found then
+    return false;
+  end if;
+  delete from legal_hold_reads where hold_id = p_id;
+  return true;
+end$$;
+
+-- a hold with its criteria and held reads, as the api returns it. null if there's no such hold.
+create or replace function alpr_util.legal_hold_json(p_id bigint, p_with_reads boolean default true)
+returns jsonb language sql stable as $$
+  select jsonb_build_object(
+    'id', h.id,
+    'case_number', h.case_number,
+    'description', h.description,
+    'created_by', h.created_by,
+    'created_at', h.created_at,
+    'released_at', h.released_at,
+    'released_by', h.released_by,
+    'held_reads', (select count(*) from legal_hold_reads r where r.hold_id = h.id),
+    'criteria', coalesce((
+      select jsonb_agg(jsonb_strip_nulls(jsonb_build_object(
+        'read_id', c.read_id, 'plate_num', c.plate_num,
+        'start_time', c.start_time at time zone 'UTC', 'end_time', c.end_time at time zone 'UTC')) order by c.id)
+      from legal_hold_criteria c where c.hold_id = h.id), '[]'::jsonb),
+    'reads', case when p_with_reads then coalesce((
+      select jsonb_agg(jsonb_build_object(
+        'alpr_id', r.alpr_id, 'read_id', r.read_id, 'plate_num', r.plate_num, 'camera_name', r.camera_name,
+        'read_time', r.read_time at time zone 'UTC', 'image_keys', r.image_keys, 'held_at', r.held_at) order by r.read_time)
+      from legal_hold_reads r where r.hold_id = h.id), '[]'::jsonb) end
+  )
+  from legal_holds h where h.id = p_id
+$$;
@@ -0,0 +1,4 @@
+-- compressed chunks have to be decompressed before compression can be turned off
+select remove_compression_policy('public.alpr', if_exists => true);
+select decompress_chunk(c, if_compressed => true) from show_chunks('public.alpr') c;
+alter table public.alpr set (timescaledb.compress = false);
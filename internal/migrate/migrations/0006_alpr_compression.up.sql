@@ -0,0 +1,17 @@
+-- =========================
+-- Compression for older alpr chunks
+-- Chunks are compressed once every read in them is 30 days old. by then late reads have stopped
+-- arriving and most searches are for recent data.
+--   segmentby camera_name: searches filtering on cameras only decompress those cameras' batches
+--   orderby read_time DESC: matches the search ORDER BY, and the per batch min/max lets the
+--     read_time BETWEEN filter skip whole batches. id and read_id are in the orderby because
+--     the primary key (id, read_time) and the (read_id, read_time) dedupe index must be covered.
+-- Indexes don't exist on compressed chunks, plate and geo filters are applied after decompression.
+-- tools/compression_report shows the storage saved and the plan for a search over compressed chunks.
+-- =========================
+alter table public.alpr set (
+  timescaledb.compress,
+  timescaledb.compress_segmentby = 'camera_name',
+  timescaledb.compress_orderby = 'read_time DESC, id DESC, read_id'
+);
+select add_compression_policy('public.alpr', compress_after => interval '30 days', if_not_exists => true);
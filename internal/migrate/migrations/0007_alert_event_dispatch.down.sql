@@ -0,0 +1,2 @@
+drop index if exists idx_hotlist_alert_events_undispatched;
+alter table hotlist_alert_events drop column if exists dispatched_at;
@@ -0,0 +1,10 @@
+-- =========================
+-- Event dispatcher: hotlist_alert_events (vendor_down, vendor_recovered) are picked up once by the
+-- leader's dispatcher (alert.RunEventDispatcher). events from before this migration count as dispatched.
+-- =========================
+alter table hotlist_alert_events add column if not exists dispatched_at timestamptz;
+update hotlist_alert_events set dispatched_at = created_at where dispatched_at is null;
+
+create index if not exists idx_hotlist_alert_events_undispatched
+  on hotlist_alert_events (id)
+  where dispatched_at is null;
@@ -0,0 +1,3 @@
+drop trigger if exists trg_alpr_cameras on public.alpr;
+drop function if exists alpr_util.cameras_track_read();
+drop table if exists cameras;
@@ -0,0 +1,49 @@
+-- =========================
+-- Cameras: one row per camera_name, kept current by a trigger on alpr so the camera picker
+-- (GET /api/alpr/v1/cameras) never has to scan the hypertable.
+-- read_count is every read ingested for the camera, retention doesn't take it back down.
+-- =========================
+create table if not exists cameras (
+  camera_name text primary key,
+  source_id   text,
+  first_seen  timestamp not null,   -- read_time, UTC without a zone like alpr
+  last_seen   timestamp not null,
+  read_count  bigint not null default 0,
+  location    geometry(Point, 4326)  -- where its latest read was
+);
+
+create or replace function alpr_util.cameras_track_read()
+returns trigger language plpgsql as $$
+begin
+  if new.camera_name is null then
+    return null;
+  end if;
+
+  insert into cameras(camera_name, source_id, first_seen, last_seen, read_count, location)
+  values (new.camera_name, new.doc->'source'->>'id', new.read_time, new.read_time, 1, new.location)
+  on conflict (camera_name) do update set
+    read_count = cameras.read_count + 1,
+    first_seen = least(cameras.first_seen, excluded.first_seen),
+    last_seen  = greatest(cameras.last_seen, excluded.last_seen),
+    source_id  = case when excluded.last_seen >= cameras.last_seen then coalesce(excluded.source_id, cameras.source_id) else cameras.source_id end,
+    location   = case when excluded.last_seen >= cameras.last_seen then coalesce(excluded.location, cameras.location) else cameras.location end;
+  return null;
+end$$;
+
+drop trigger if exists trg_alpr_cameras on public.alpr;
+create trigger trg_alpr_cameras
+after insert on public.alpr
+for each row execute function alpr_util.cameras_track_read();
+
+-- backfill from what's already stored. one pass over alpr, only ever runs with this migration
+insert into cameras(camera_name, source_id, first_seen, last_seen, read_count, location)
+select camera_name,
+       (array_agg(doc->'source'->>'id' order by read_time desc))[1],
+       min(read_time),
+       max(read_time),
+       count(*),
+       (array_agg(location order by read_time desc))[1]
+from public.alpr
+where camera_name is not null
+group by camera_name
+on conflict (camera_name) do nothing;
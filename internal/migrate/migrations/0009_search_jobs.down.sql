@@ -0,0 +1,2 @@
+drop table if exists search_job_results;
+drop table if exists search_jobs;
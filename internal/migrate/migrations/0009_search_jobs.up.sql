@@ -0,0 +1,30 @@
+-- =========================
+-- Search jobs: searches too broad to run inside a request (internal/api/searchjobs).
+-- a job is queued by the api, claimed by a worker, and its matching reads are copied into
+-- search_job_results in result order, where they can be paged or exported until the job expires.
+-- =========================
+create table if not exists search_jobs (
+  id            bigserial primary key,
+  status        text not null default 'queued',  -- queued, running, done, failed, cancelled
+  doc           jsonb not null,                  -- the SearchDoc
+  rows          bigint not null default 0,       -- reads found so far
+  progress      double precision,                -- 0-1, null when it can't be told (sorted by similarity)
+  error         text,
+  worker_id     text,
+  attempts      integer not null default 0,
+  created_at    timestamptz not null default now(),
+  started_at    timestamptz,
+  heartbeat_at  timestamptz,                     -- a running job that stops beating is claimed again
+  finished_at   timestamptz,
+  constraint search_jobs_status_ok check (status in ('queued', 'running', 'done', 'failed', 'cancelled'))
+);
+create index if not exists idx_search_jobs_pending on search_jobs (id) where status in ('queued', 'running');
+create index if not exists idx_search_jobs_finished_at on search_jobs (finished_at) where finished_at is not null;
+
+-- seq is the read's 1 based position in the results, pages are seq ranges
+create table if not exists search_job_results (
+  job_id  bigint not null references search_jobs(id) on delete cascade,
+  seq     bigint not null,
+  record  jsonb not null,                        -- the AlprRecord, image urls are presigned when it's served
+  primary key (job_id, seq)
+);
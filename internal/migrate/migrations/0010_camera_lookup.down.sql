@@ -0,0 +1,28 @@
+drop function if exists alpr_util.cameras_refresh(interval);
+drop table if exists cameras_refresh;
+drop index if exists idx_alpr_inserted_at;
+drop index if exists idx_alpr_lower_camera_name;
+
+-- back to the 0008 trigger. reads inserted since the last refresh aren't counted
+create or replace function alpr_util.cameras_track_read()
+returns trigger language plpgsql as $$
+begin
+  if new.camera_name is null then
+    return null;
+  end if;
+
+  insert into cameras(camera_name, source_id, first_seen, last_seen, read_count, location)
+  values (new.camera_name, new.doc->'source'->>'id', new.read_time, new.read_time, 1, new.location)
+  on conflict (camera_name) do update set
+    read_count = cameras.read_count + 1,
+    first_seen = least(cameras.first_seen, excluded.first_seen),
+    last_seen  = greatest(cameras.last_seen, excluded.last_seen),
+    source_id  = case when excluded.last_seen >= cameras.last_seen then coalesce(excluded.source_id, cameras.source_id) else cameras.source_id end,
+    location   = case when excluded.last_seen >= cameras.last_seen then coalesce(excluded.location, cameras.location) else cameras.location end;
+  return null;
+end$$;
+
+drop trigger if exists trg_alpr_cameras on public.alpr;
+create trigger trg_alpr_cameras
+after insert on public.alpr
+for each row execute function alpr_util.cameras_track_read();
@@ -0,0 +1,62 @@
+-- =========================
+-- Camera lookups.
+-- camera_names in a search compares LOWER(camera_name) for plain names, this index serves it the way
+-- idx_read_time_camera_name served the old exact IN match.
+--
+-- The cameras table is now kept current by a periodic job (cameras.RunRefresh, on the leader) instead of the
+-- trigger from 0008. the trigger upserted the camera's row on every read: lock contention on busy cameras
+-- and a dead tuple per read. the job folds in a window of reads at a time by inserted_at, from where the last
+-- run stopped up to a minute ago. the minute lets in flight ingest transactions commit, inserted_at is when
+-- their transaction started. reads taken more than 7 days before they arrive aren't folded in, the lookback
+-- keeps the scan on recent, uncompressed chunks where idx_alpr_inserted_at is.
+-- =========================
+create index if not exists idx_alpr_lower_camera_name on public.alpr (lower(camera_name), read_time desc);
+create index if not exists idx_alpr_inserted_at on public.alpr (inserted_at);
+
+drop trigger if exists trg_alpr_cameras on public.alpr;
+drop function if exists alpr_util.cameras_track_read();
+
+create table if not exists cameras_refresh (
+  id             boolean primary key default true check (id),  -- one row
+  inserted_from  timestamp not null                              -- reads inserted from here on aren't in cameras yet
+);
+
+-- the trigger counted everything inserted before now
+insert into cameras_refresh(inserted_from) values (localtimestamp(0)) on conflict (id) do nothing;
+
+-- fold reads inserted since the last refresh into cameras, returns how many cameras were touched.
+create or replace function alpr_util.cameras_refresh(p_lookback interval default interval '7 days')
+returns bigint language plpgsql as $$
+declare
+  v_from timestamp;
+  v_to   timestamp := localtimestamp(0) - interval '1 minute';  -- same clock as inserted_at's default
+  n      bigint;
+begin
+  select inserted_from into v_from from cameras_refresh for update;
+  if v_from is null or v_from >= v_to then
+    return 0;
+  end if;
+
+  insert into cameras(camera_name, source_id, first_seen, last_seen, read_count, location)
+  select camera_name,
+         (array_agg(doc->'source'->>'id' order by read_time desc))[1],
+         min(read_time),
+         max(read_time),
+         count(*),
+         (array_agg(location order by read_time desc))[1]
+  from public.alpr
+  where camera_name is not null
+    and inserted_at >= v_from and inserted_at < v_to
+    and read_time >= v_from - p_lookback
+  group by camera_name
+  on conflict (camera_name) do update set
+    read_count = cameras.read_count + excluded.read_count,
+    first_seen = least(cameras.first_seen, excluded.first_seen),
+    last_seen  = greatest(cameras.last_seen, excluded.last_seen),
+    source_id  = case when excluded.last_seen >= cameras.last_seen then coalesce(excluded.source_id, cameras.source_id) else cameras.source_id end,
+    location   = case when excluded.last_seen >= cameras.last_seen then coalesce(excluded.location, cameras.location) else cameras.location end;
+  get diagnostics n = row_count;
+
+  update cameras_refresh set inserted_from = v_to;
+  return n;
+end$$;
@@ -2,15 +2,70 @@ package repository
 
 import (
 	"context"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Eyemetric/alpr_service/internal/db"
+	"github.com/jackc/pgx/v5"
 )
 
+// outcomes reported by alpr_util.ingest_alpr
+const (
+	IngestOK         = "ok"
+	IngestDuplicate  = "duplicate"
+	IngestDeadletter = "deadletter"
+)
+
+// IngestResult is the parsed return value of alpr_util.ingest_alpr ("<outcome>:<stage>[:<alpr id>]").
+// ID is the stored alpr row, for a duplicate it's the row that was already there.
+type IngestResult struct {
+	Outcome string `json:"outcome"`
+	Stage   string `json:"stage,omitempty"`
+	ID      int64  `json:"id,omitempty"`
+}
+
+func ParseIngestResult(res string) IngestResult {
+	parts := strings.SplitN(res, ":", 3)
+	r := IngestResult{Outcome: parts[0]}
+	if len(parts) > 1 {
+		r.Stage = parts[1]
+	}
+	if len(parts) > 2 {
+		r.ID, _ = strconv.ParseInt(parts[2], 10, 64)
+	}
+	return r
+}
+
 type ALPRRepository interface {
-	IngestPlateRead(ctx context.Context, doc []byte) (string, error)
+	IngestPlateRead(ctx context.Context, doc []byte) (IngestResult, error)
 	AddHotlist(ctx context.Context, hotlist []byte) (int32, error)
 	ScheduleSuccess(ctx context.Context, id int64) error
 	ScheduleFailure(ctx context.Context, failureParams db.ScheduleFailureParams) error
 	ClaimDue(ctx context.Context, claimDueParams db.ClaimDueParams) ([]db.ClaimDueRow, error)
+	ReclaimStuck(ctx context.Context) (int32, error)
+	ClaimAlertEvents(ctx context.Context, max int32) ([]db.ClaimAlertEventsRow, error)
 	GetPlateHit(ctx context.Context, plateHitParams db.GetPlateHitParams) ([]db.GetPlateHitRow, error)
+	GetAlertState(ctx context.Context) (db.GetAlertStateRow, error)
+	Ping(ctx context.Context) error
+	CreateLegalHold(ctx context.Context, doc []byte) (int64, error)
+	GetLegalHold(ctx context.Context, id int64) ([]byte, error)
+	ListLegalHolds(ctx context.Context, activeOnly bool) ([][]byte, error)
+	ReleaseLegalHold(ctx context.Context, params db.ReleaseLegalHoldParams) (bool, error)
+	CaptureLegalHolds(ctx context.Context, tx pgx.Tx, before time.Time) (int64, error)
+	ListCameras(ctx context.Context, pattern string) ([]db.ListCamerasRow, error)
+	RefreshCameras(ctx context.Context) (int64, error)
+	ListDeadletter(ctx context.Context, params db.ListDeadletterParams) ([]int64, error)
+	ReprocessDeadletter(ctx context.Context, id int64) (IngestResult, error)
+	CreateSearchJob(ctx context.Context, doc []byte) (int64, error)
+	GetSearchJob(ctx context.Context, id int64) (*db.SearchJob, error)
+	CancelSearchJob(ctx context.Context, id int64) (bool, error)
+	ClaimSearchJob(ctx context.Context, workerID string, staleBefore time.Time) (*db.ClaimSearchJobRow, error)
+	SearchJobHeartbeat(ctx context.Context, params db.SearchJobHeartbeatParams) (string, error)
+	UpdateSearchJobProgress(ctx context.Context, params db.UpdateSearchJobProgressParams) error
+	FinishSearchJob(ctx context.Context, params db.FinishSearchJobParams) error
+	AddSearchJobResults(ctx context.Context, params db.AddSearchJobResultsParams) (bool, error)
+	ClearSearchJobResults(ctx context.Context, jobID int64) error
+	ListSearchJobResults(ctx context.Context, params db.ListSearchJobResultsParams) ([][]byte, error)
+	DeleteExpiredSearchJobs(ctx context.Context, before time.Time) (int64, error)
 }
@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Eyemetric/alpr_service/internal/db"
-	_ "github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -22,14 +25,13 @@ func NewPgxAlprRepo(pool *pgxpool.Pool) *PgxAlprRepo {
 	}
 }
 
-func (a *PgxAlprRepo) IngestPlateRead(ctx context.Context, doc []byte) (string, error) {
+func (a *PgxAlprRepo) IngestPlateRead(ctx context.Context, doc []byte) (IngestResult, error) {
 	res, err := a.queries.IngestALPR(ctx, doc)
 	if err != nil {
-		return "", fmt.Errorf("failed to ingest plate read: %w", err)
+		return IngestResult{}, fmt.Errorf("failed to ingest plate read: %w", err)
 	}
-	fmt.Println("failures go in a deadletter table")
 	fmt.Printf("ingest result: %+v\n", res)
-	return "", nil
+	return ParseIngestResult(res), nil
 }
 
 func (a *PgxAlprRepo) AddHotlist(ctx context.Context, hotlist []byte) (int32, error) {
@@ -71,6 +73,24 @@ func (a *PgxAlprRepo) ClaimDue(ctx context.Context, claimDueParams db.ClaimDuePa
 	return claimsDue, nil
 }
 
+// ReclaimStuck puts alerts whose worker died mid send back in the queue, returns how many.
+func (a *PgxAlprRepo) ReclaimStuck(ctx context.Context) (int32, error) {
+	n, err := a.queries.ReclaimStuck(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reclaim stuck alerts: %w", err)
+	}
+	return n, nil
+}
+
+// ClaimAlertEvents marks up to max undispatched events dispatched and returns them.
+func (a *PgxAlprRepo) ClaimAlertEvents(ctx context.Context, max int32) ([]db.ClaimAlertEventsRow, error) {
+	events, err := a.queries.ClaimAlertEvents(ctx, max)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim alert events: %w", err)
+	}
+	return events, nil
+}
+
 func (a *PgxAlprRepo) GetPlateHit(ctx context.Context, plateHitParams db.GetPlateHitParams) ([]db.GetPlateHitRow, error) {
 	hits, err := a.queries.GetPlateHit(ctx, plateHitParams)
 	if err != nil {
@@ -79,3 +99,205 @@ func (a *PgxAlprRepo) GetPlateHit(ctx context.Context, plateHitParams db.GetPlat
 
 	return hits, nil
 }
+
+func (a *PgxAlprRepo) GetAlertState(ctx context.Context) (db.GetAlertStateRow, error) {
+	state, err := a.queries.GetAlertState(ctx)
+	if err != nil {
+		return db.GetAlertStateRow{}, err
+	}
+	return state, nil
+}
+
+func (a *PgxAlprRepo) Ping(ctx context.Context) error {
+	return a.dbpool.Ping(ctx)
+}
+
+func (a *PgxAlprRepo) CreateLegalHold(ctx context.Context, doc []byte) (int64, error) {
+	id, err := a.queries.CreateLegalHold(ctx, doc)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create legal hold: %w", err)
+	}
+	return id, nil
+}
+
+// GetLegalHold returns the hold as json, nil if there's no hold with that id.
+func (a *PgxAlprRepo) GetLegalHold(ctx context.Context, id int64) ([]byte, error) {
+	return a.queries.GetLegalHold(ctx, id)
+}
+
+func (a *PgxAlprRepo) ListLegalHolds(ctx context.Context, activeOnly bool) ([][]byte, error) {
+	return a.queries.ListLegalHolds(ctx, activeOnly)
+}
+
+// ReleaseLegalHold is false when the hold doesn't exist or was already released.
+func (a *PgxAlprRepo) ReleaseLegalHold(ctx context.Context, params db.ReleaseLegalHoldParams) (bool, error) {
+	return a.queries.ReleaseLegalHold(ctx, params)
+}
+
+// CaptureLegalHolds copies reads older than before that match an active hold out of the hypertable.
+// before is compared against alpr.read_time, which is UTC without a zone. runs in tx, the retention purge drops
+// the chunks in the same transaction.
+func (a *PgxAlprRepo) CaptureLegalHolds(ctx context.Context, tx pgx.Tx, before time.Time) (int64, error) {
+	n, err := a.queries.WithTx(tx).CaptureLegalHolds(ctx, pgtype.Timestamp{Time: before.UTC(), Valid: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to capture legal holds: %w", err)
+	}
+	return n, nil
+}
+
+// ListDeadletter returns deadletter ids oldest first.
+func (a *PgxAlprRepo) ListDeadletter(ctx context.Context, params db.ListDeadletterParams) ([]int64, error) {
+	ids, err := a.queries.ListDeadletter(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deadletter: %w", err)
+	}
+	return ids, nil
+}
+
+// ReprocessDeadletter runs a deadletter doc through ingest again and removes the deadletter row.
+// a doc that fails again is dead-lettered under a new id by ingest_alpr, so the old row goes either way.
+// the outcome is "not_found" when there's no row with that id.
+func (a *PgxAlprRepo) ReprocessDeadletter(ctx context.Context, id int64) (IngestResult, error) {
+	var res string
+	err := pgx.BeginFunc(ctx, a.dbpool, func(tx pgx.Tx) error {
+		q := a.queries.WithTx(tx)
+		var err error
+		if res, err = q.ReprocessDeadletter(ctx, id); err != nil {
+			return err
+		}
+		if res == "not_found" {
+			return nil
+		}
+		return q.DeleteDeadletter(ctx, id)
+	})
+	if err != nil {
+		return IngestResult{}, fmt.Errorf("failed to reprocess deadletter %d: %w", id, err)
+	}
+	return ParseIngestResult(res), nil
+}
+
+// ListCameras returns every camera we've had a read from, pattern is an ilike pattern, empty for all.
+func (a *PgxAlprRepo) ListCameras(ctx context.Context, pattern string) ([]db.ListCamerasRow, error) {
+	cameras, err := a.queries.ListCameras(ctx, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cameras: %w", err)
+	}
+	return cameras, nil
+}
+
+// RefreshCameras folds reads inserted since the last refresh into the cameras table, returns how many cameras changed.
+func (a *PgxAlprRepo) RefreshCameras(ctx context.Context) (int64, error) {
+	n, err := a.queries.RefreshCameras(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to refresh cameras: %w", err)
+	}
+	return n, nil
+}
+
+func (a *PgxAlprRepo) CreateSearchJob(ctx context.Context, doc []byte) (int64, error) {
+	id, err := a.queries.CreateSearchJob(ctx, doc)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create search job: %w", err)
+	}
+	return id, nil
+}
+
+// GetSearchJob returns nil if there's no job with that id.
+func (a *PgxAlprRepo) GetSearchJob(ctx context.Context, id int64) (*db.SearchJob, error) {
+	job, err := a.queries.GetSearchJob(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search job %d: %w", id, err)
+	}
+	return &job, nil
+}
+
+// CancelSearchJob is false when the job doesn't exist or already finished.
+func (a *PgxAlprRepo) CancelSearchJob(ctx context.Context, id int64) (bool, error) {
+	n, err := a.queries.CancelSearchJob(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel search job %d: %w", id, err)
+	}
+	return n > 0, nil
+}
+
+// ClaimSearchJob takes the oldest queued job, or a running one whose worker stopped beating before staleBefore.
+// nil when there's nothing to do.
+func (a *PgxAlprRepo) ClaimSearchJob(ctx context.Context, workerID string, staleBefore time.Time) (*db.ClaimSearchJobRow, error) {
+	job, err := a.queries.ClaimSearchJob(ctx, db.ClaimSearchJobParams{
+		WorkerID:    workerID,
+		StaleBefore: pgtype.Timestamptz{Time: staleBefore, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim search job: %w", err)
+	}
+	return &job, nil
+}
+
+// SearchJobHeartbeat keeps a running job claimed and returns its status, "" when the worker no longer owns it.
+func (a *PgxAlprRepo) SearchJobHeartbeat(ctx context.Context, params db.SearchJobHeartbeatParams) (string, error) {
+	status, err := a.queries.SearchJobHeartbeat(ctx, params)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to heartbeat search job %d: %w", params.ID, err)
+	}
+	return status, nil
+}
+
+func (a *PgxAlprRepo) UpdateSearchJobProgress(ctx context.Context, params db.UpdateSearchJobProgressParams) error {
+	if err := a.queries.UpdateSearchJobProgress(ctx, params); err != nil {
+		return fmt.Errorf("failed to update search job %d: %w", params.ID, err)
+	}
+	return nil
+}
+
+// FinishSearchJob records the outcome, unless the job was cancelled or claimed by another worker meanwhile.
+func (a *PgxAlprRepo) FinishSearchJob(ctx context.Context, params db.FinishSearchJobParams) error {
+	if err := a.queries.FinishSearchJob(ctx, params); err != nil {
+		return fmt.Errorf("failed to finish search job %d: %w", params.ID, err)
+	}
+	return nil
+}
+
+// AddSearchJobResults stores records as results FirstSeq, FirstSeq+1, ... false when nothing was stored
+// because the job was cancelled or claimed by another worker meanwhile.
+func (a *PgxAlprRepo) AddSearchJobResults(ctx context.Context, params db.AddSearchJobResultsParams) (bool, error) {
+	n, err := a.queries.AddSearchJobResults(ctx, params)
+	if err != nil {
+		return false, fmt.Errorf("failed to store search job %d results: %w", params.JobID, err)
+	}
+	return n > 0, nil
+}
+
+// ClearSearchJobResults drops what an earlier attempt at the job stored.
+func (a *PgxAlprRepo) ClearSearchJobResults(ctx context.Context, jobID int64) error {
+	if err := a.queries.ClearSearchJobResults(ctx, jobID); err != nil {
+		return fmt.Errorf("failed to clear search job %d results: %w", jobID, err)
+	}
+	return nil
+}
+
+// ListSearchJobResults returns up to MaxRows records after AfterSeq, in result order.
+func (a *PgxAlprRepo) ListSearchJobResults(ctx context.Context, params db.ListSearchJobResultsParams) ([][]byte, error) {
+	records, err := a.queries.ListSearchJobResults(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list search job %d results: %w", params.JobID, err)
+	}
+	return records, nil
+}
+
+// DeleteExpiredSearchJobs removes jobs, and their results, that finished before before.
+func (a *PgxAlprRepo) DeleteExpiredSearchJobs(ctx context.Context, before time.Time) (int64, error) {
+	n, err := a.queries.DeleteExpiredSearchJobs(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired search jobs: %w", err)
+	}
+	return n, nil
+}
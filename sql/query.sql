@@ -13,6 +13,10 @@ select alpr_util.hotlist_alert_schedule_failure(sqlc.arg(id), sqlc.arg(err));
 -- name: ReclaimStuck :one
 select alpr_util.alerts_reclaim_stuck();
 
+-- name: GetAlertState :one
+select mode::text as mode, phase_attempts, first_failed_at, next_due_at
+from hotlist_alert_state where id = 1;
+
 -- name: ClaimDue :many
 select
     id::bigint as id,
@@ -55,3 +59,113 @@ select
   -- not using next_wake(). using a 5 sec. db poll. simpler
   -- name: NextWake :one
   select alpr_util.next_wake();
+
+-- name: CreateLegalHold :one
+select alpr_util.legal_hold_create(@doc::jsonb)::bigint as id;
+
+-- name: GetLegalHold :one
+select alpr_util.legal_hold_json(@id::bigint) as hold;
+
+-- name: ListLegalHolds :many
+select alpr_util.legal_hold_json(id, false) as hold
+from legal_holds
+where (not @active_only::boolean or released_at is null)
+order by created_at desc;
+
+-- name: ReleaseLegalHold :one
+select alpr_util.legal_hold_release(@id::bigint, @released_by::text)::boolean as released;
+
+-- name: CaptureLegalHolds :one
+select alpr_util.legal_hold_capture(null, @before::timestamp)::bigint as captured;
+
+-- name: ListDeadletter :many
+select id from public.alpr_deadletter
+where (@stage::text = '' or stage = @stage::text)
+  and failed_at >= @since::timestamptz
+order by id
+limit @max_rows::integer;
+
+-- name: ReprocessDeadletter :one
+select public.reprocess_deadletter(@id::bigint) as result;
+
+-- name: DeleteDeadletter :exec
+delete from public.alpr_deadletter where id = @id::bigint;
+
+-- name: ClaimAlertEvents :many
+update hotlist_alert_events e set dispatched_at = now()
+where e.id in (
+  select id from hotlist_alert_events
+  where dispatched_at is null
+  order by id
+  limit @max_rows::integer
+  for update skip locked)
+returning e.id, e.kind, e.created_at, e.details;
+
+-- name: ListCameras :many
+select camera_name, source_id, first_seen, last_seen, read_count,
+  case when location is not null then jsonb_build_object('lat', trunc(st_y(location)::numeric, 5), 'lon', trunc(st_x(location)::numeric, 5)) end as location
+from cameras
+where (@pattern::text = '' or camera_name ilike @pattern::text)
+order by camera_name;
+
+-- name: RefreshCameras :one
+select alpr_util.cameras_refresh()::bigint as cameras;
+
+-- name: CreateSearchJob :one
+insert into search_jobs (doc) values (@doc::jsonb) returning id;
+
+-- name: GetSearchJob :one
+select id, status, doc, rows, progress, error, worker_id, attempts, created_at, started_at, heartbeat_at, finished_at
+from search_jobs where id = @id::bigint;
+
+-- name: CancelSearchJob :execrows
+update search_jobs set status = 'cancelled', finished_at = now()
+where id = @id::bigint and status in ('queued', 'running');
+
+-- name: ClaimSearchJob :one
+update search_jobs j set status = 'running', worker_id = @worker_id::text, attempts = j.attempts + 1,
+  started_at = now(), heartbeat_at = now(), rows = 0, progress = null, error = null
+where j.id = (
+  select id from search_jobs
+  where status = 'queued' or (status = 'running' and heartbeat_at < @stale_before::timestamptz)
+  order by id
+  limit 1
+  for update skip locked)
+returning j.id, j.doc, j.attempts;
+
+-- name: SearchJobHeartbeat :one
+update search_jobs set heartbeat_at = now()
+where id = @id::bigint and worker_id = @worker_id::text
+returning status;
+
+-- name: UpdateSearchJobProgress :exec
+update search_jobs set rows = @rows::bigint, progress = sqlc.narg(progress)::float8, heartbeat_at = now()
+where id = @id::bigint and worker_id = @worker_id::text and status = 'running';
+
+-- name: FinishSearchJob :exec
+update search_jobs set status = @status::text, error = sqlc.narg(error)::text, rows = @rows::bigint,
+  progress = case when @status::text = 'done' then 1 else progress end, finished_at = now()
+where id = @id::bigint and worker_id = @worker_id::text and status = 'running';
+
+-- only while the worker still owns the running job. the share lock holds off a claim by another worker
+-- until the insert commits, so its clear of the results can't be overtaken by a stale batch.
+-- name: AddSearchJobResults :execrows
+with owner as (
+  select id from search_jobs
+  where id = @job_id::bigint and worker_id = @worker_id::text and status = 'running'
+  for share)
+insert into search_job_results (job_id, seq, record)
+select owner.id, @first_seq::bigint + t.o - 1, t.r
+from owner, unnest(@records::jsonb[]) with ordinality as t(r, o);
+
+-- name: ClearSearchJobResults :exec
+delete from search_job_results where job_id = @job_id::bigint;
+
+-- name: ListSearchJobResults :many
+select record from search_job_results
+where job_id = @job_id::bigint and seq > @after_seq::bigint
+order by seq
+limit @max_rows::integer;
+
+-- name: DeleteExpiredSearchJobs :execrows
+delete from search_jobs where finished_at < @before::timestamptz;
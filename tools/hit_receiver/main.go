@@ -1,7 +1,175 @@
 package main
 
-import "fmt"
+/* hit_receiver stands in for NJSNAP's plate hit endpoint so alerts can be tested locally.
+It logs every plate hit it receives and answers 200.
+
+By default it listens over plain http on :8081 (the dev PLATEHIT_URL).
+Set HIT_TLS=1 to serve https with a throwaway self signed cert, or HIT_TLS_CERT/HIT_TLS_KEY to use your own.
+The service only accepts the self signed cert with NJSNAP_TLS_INSECURE=true in dev mode,
+or by pointing NJSNAP_CA_FILE at HIT_TLS_CERT.
+
+Set HIT_SIGNING_SECRET to the service's NJSNAP_SIGNING_SECRET to check signatures the way NJSNAP would:
+unsigned, tampered, stale or replayed hits get a 401. Retries carrying an Idempotency-Key
+we've already accepted are answered 200 and not counted twice.
+*/
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Eyemetric/alpr_service/internal/api/alert"
+)
+
+// helper
+func getEnv(key string, fallback string) string {
+	if val, ok := os.LookupEnv(key); ok {
+		return val
+	}
+	return fallback
+}
 
 func main() {
-	fmt.Println("coming soon")
+	addr := getEnv("HIT_ADDR", ":8081")
+	certFile := getEnv("HIT_TLS_CERT", "")
+	keyFile := getEnv("HIT_TLS_KEY", "")
+	selfSigned := getEnv("HIT_TLS", "") != ""
+
+	recv := &receiver{seen: map[string]time.Time{}}
+	if secret := getEnv("HIT_SIGNING_SECRET", ""); secret != "" {
+		recv.secret = []byte(secret)
+		log.Println("verifying hit signatures")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/poi/alpr", recv.receiveHit)
+
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	switch {
+	case certFile != "":
+		log.Printf("listening on https://%s (cert %s)\n", addr, certFile)
+		log.Fatal(server.ListenAndServeTLS(certFile, keyFile))
+	case selfSigned:
+		cert, err := selfSignedCert()
+		if err != nil {
+			log.Fatal(err)
+		}
+		pin := sha256.Sum256(cert.Leaf.RawSubjectPublicKeyInfo)
+		log.Printf("listening on https://%s with a self signed cert. pin: %s\n", addr, base64.StdEncoding.EncodeToString(pin[:]))
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		log.Fatal(server.ListenAndServeTLS("", ""))
+	default:
+		log.Printf("listening on http://%s\n", addr)
+		log.Fatal(server.ListenAndServe())
+	}
+}
+
+type receiver struct {
+	secret []byte
+	mu     sync.Mutex
+	seen   map[string]time.Time //nonces and idempotency keys, pruned after the skew window
+}
+
+// remember records key and reports whether it was already there.
+func (rc *receiver) remember(key string) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := time.Now()
+	for k, at := range rc.seen {
+		if now.Sub(at) > alert.DefaultMaxSkew {
+			delete(rc.seen, k)
+		}
+	}
+	if _, ok := rc.seen[key]; ok {
+		return true
+	}
+	rc.seen[key] = now
+	return false
+}
+
+func (rc *receiver) receiveHit(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if rc.secret != nil {
+		nonce, err := alert.VerifySignature(r.Header, body, rc.secret, alert.DefaultMaxSkew, time.Now())
+		if err != nil {
+			log.Printf("rejected hit: %v\n", err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if rc.remember("nonce:" + nonce) {
+			log.Println("rejected hit: replayed nonce")
+			http.Error(w, "replayed request", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if key := r.Header.Get(alert.HeaderIdempotencyKey); key != "" && rc.remember("key:"+key) {
+		log.Printf("duplicate hit %s, already processed\n", key)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var hits struct {
+		PlateHits []map[string]any `json:"plateHits"`
+	}
+	if err := json.Unmarshal(body, &hits); err != nil {
+		http.Error(w, "body is not a plate hit document", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("received %d plate hit(s), token=%q\n", len(hits.PlateHits), r.Header.Get("AuthToken"))
+	for _, hit := range hits.PlateHits {
+		log.Printf("  plate=%v camera=%v time=%v\n", hit["plateNumber"], hit["cameraName"], hit["eventDateTime"])
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "hit_receiver"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
 }